@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func Fatal(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+/*
+Golden cases
+*/
+
+// one example input of a day and the answer lines it has to print
+type Case struct {
+	Day   string
+	Input string
+	Want  []string
+}
+
+/*
+read golden.txt. every row is "<day> <input> <expected line>", rows with the
+same day and input are collected into one case. '#' starts a comment row.
+*/
+func ReadGolden(path string) ([]Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cases := []Case{}
+	index := map[[2]string]int{}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%s:%d: want \"<day> <input> <expected line>\", got %q", path, lineNo, line)
+		}
+		key := [2]string{parts[0], parts[1]}
+		if _, ok := index[key]; !ok {
+			index[key] = len(cases)
+			cases = append(cases, Case{Day: parts[0], Input: parts[1]})
+		}
+		cases[index[key]].Want = append(cases[index[key]].Want, parts[2])
+	}
+	return cases, scanner.Err()
+}
+
+/*
+an output line matches when it equals the expected line or starts with it
+followed by a space (eg. a route printed after the answer). an expected
+line starting with ... only has to end the output line, day 10 prints its
+answer on stderr right after the drawing without a newline in between.
+*/
+func HasAnswer(output string, want string) bool {
+	suffix, anyStart := strings.CutPrefix(want, "...")
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == want || strings.HasPrefix(line, want+" ") || anyStart && strings.HasSuffix(line, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Building and running days
+*/
+
+/*
+copy a day directory into a fresh temporary directory. the go tool rewrites
+go.mod files without a go directive, working on a copy keeps the tree clean
+and lets the mutation pass edit sources freely.
+*/
+func CopyDay(dayDir string) (string, error) {
+	tmp, err := os.MkdirTemp("", filepath.Base(dayDir)+"-")
+	if err != nil {
+		return "", err
+	}
+	// days can have packages of their own in sub directories
+	err = filepath.WalkDir(dayDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dayDir, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(tmp, rel), 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(tmp, rel), data, 0644)
+	})
+	return tmp, err
+}
+
+// run a command in dir and return its combined stdout and stderr
+func Run(ctx context.Context, dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.String(), err
+}
+
+/*
+build the day in dir into dir/solution, with coverage counters if cover is set
+*/
+func Build(dir string, cover bool) error {
+	args := []string{"build", "-o", "solution"}
+	if cover {
+		args = append(args, "-cover")
+	}
+	out, err := Run(context.Background(), dir, nil, "go", append(args, ".")...)
+	if err != nil {
+		return fmt.Errorf("go build in %s: %w\n%s", dir, err, out)
+	}
+	return nil
+}
+
+/*
+run the built solution on every case of the day. the inputs are read from
+the copied directory. returns the expected lines that were missing, a run
+that times out misses all of them. solutions that panic after printing
+their answers still pass, day 22 does that on the example.
+*/
+func RunCases(dir string, cases []Case, env []string, timeout time.Duration) []string {
+	missing := []string{}
+	for _, c := range cases {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		out, _ := Run(ctx, dir, env, filepath.Join(dir, "solution"), c.Input)
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+		for _, want := range c.Want {
+			if timedOut || !HasAnswer(out, want) {
+				missing = append(missing, c.Input+": "+want)
+			}
+		}
+	}
+	return missing
+}
+
+/*
+Coverage
+*/
+
+/*
+build the day with coverage, run its golden cases and turn the counters into
+an html report in outDir. returns the coverage summary printed by covdata.
+*/
+func Coverage(dir string, day string, cases []Case, outDir string, timeout time.Duration) (string, []string, error) {
+	if err := Build(dir, true); err != nil {
+		return "", nil, err
+	}
+	counters := filepath.Join(dir, "covdata")
+	if err := os.MkdirAll(counters, 0755); err != nil {
+		return "", nil, err
+	}
+	missing := RunCases(dir, cases, []string{"GOCOVERDIR=" + counters}, timeout)
+
+	profile := filepath.Join(dir, "cover.out")
+	if out, err := Run(context.Background(), dir, nil, "go", "tool", "covdata", "textfmt", "-i="+counters, "-o="+profile); err != nil {
+		return "", missing, fmt.Errorf("covdata textfmt: %w\n%s", err, out)
+	}
+	report, err := filepath.Abs(filepath.Join(outDir, day+".html"))
+	if err != nil {
+		return "", missing, err
+	}
+	if out, err := Run(context.Background(), dir, nil, "go", "tool", "cover", "-html="+profile, "-o="+report); err != nil {
+		return "", missing, fmt.Errorf("cover -html: %w\n%s", err, out)
+	}
+	percent, err := Run(context.Background(), dir, nil, "go", "tool", "covdata", "percent", "-i="+counters)
+	if err != nil {
+		return "", missing, fmt.Errorf("covdata percent: %w\n%s", err, percent)
+	}
+	// "	main		coverage: 89.0% of statements", one line per package
+	parts := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(percent), "\n") {
+		pkg, rest, found := strings.Cut(strings.TrimSpace(line), "coverage:")
+		if !found {
+			continue
+		}
+		part := strings.TrimSpace(rest)
+		if pkg = strings.TrimSpace(pkg); pkg != "main" {
+			part = pkg + " " + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", "), missing, nil
+}
+
+/*
+Mutation testing
+*/
+
+// the comparison operators and what each one is flipped to
+var Flips = map[token.Token]token.Token{
+	token.LSS: token.GEQ,
+	token.GEQ: token.LSS,
+	token.GTR: token.LEQ,
+	token.LEQ: token.GTR,
+	token.EQL: token.NEQ,
+	token.NEQ: token.EQL,
+}
+
+// a single flipped comparison operator in one source file
+type Mutant struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+	From   token.Token
+	To     token.Token
+}
+
+func (m Mutant) String() string {
+	return fmt.Sprintf("%s:%d:%d %s -> %s", m.File, m.Line, m.Column, m.From, m.To)
+}
+
+/*
+find every comparison in the go files of the day directory and of the
+packages in its sub directories. comparisons between constants are skipped,
+flipping them can not change behaviour that depends on the input. the File
+of a mutant is relative to dir.
+*/
+func FindMutants(dir string) ([]Mutant, error) {
+	mutants := []Mutant{}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			expr, ok := n.(*ast.BinaryExpr)
+			if !ok {
+				return true
+			}
+			to, ok := Flips[expr.Op]
+			if !ok {
+				return true
+			}
+			_, leftLit := expr.X.(*ast.BasicLit)
+			_, rightLit := expr.Y.(*ast.BasicLit)
+			if leftLit && rightLit {
+				return true
+			}
+			pos := fset.Position(expr.OpPos)
+			mutants = append(mutants, Mutant{rel, pos.Offset, pos.Line, pos.Column, expr.Op, to})
+			return true
+		})
+		return nil
+	})
+	return mutants, err
+}
+
+/*
+apply a mutant to the copied sources, build and run the golden cases.
+the mutant is killed when the build fails or any expected line goes missing.
+the original file is put back afterwards.
+*/
+func TryMutant(dir string, m Mutant, cases []Case, timeout time.Duration) (bool, error) {
+	path := filepath.Join(dir, m.File)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer os.WriteFile(path, original, 0644)
+
+	mutated := make([]byte, 0, len(original)+1)
+	mutated = append(mutated, original[:m.Offset]...)
+	mutated = append(mutated, m.To.String()...)
+	mutated = append(mutated, original[m.Offset+len(m.From.String()):]...)
+	if err := os.WriteFile(path, mutated, 0644); err != nil {
+		return false, err
+	}
+	if err := Build(dir, false); err != nil {
+		return true, nil
+	}
+	return len(RunCases(dir, cases, nil, timeout)) > 0, nil
+}
+
+/*
+aoc coverage [-root dir] [-out dir] [-mutate] [-timeout d] [day_01 day_02 ...]
+
+runs the golden cases of each day with a coverage build, writes an html
+report per day and prints a summary. with -mutate every comparison operator
+of the day is flipped one at a time, and mutants that still produce all
+golden answers are listed as survivors.
+*/
+func CoverageCommand(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("coverage", flag.ExitOnError)
+	root := flags.String("root", "..", "repository root with the day_XX directories")
+	golden := flags.String("golden", "golden.txt", "file with the golden answers")
+	outDir := flags.String("out", "coverage", "directory for the html reports")
+	mutate := flags.Bool("mutate", false, "also run the mutation testing pass")
+	timeout := flags.Duration("timeout", 30*time.Second, "time limit for one run of a solution")
+	flags.Parse(args)
+
+	cases, err := ReadGolden(*golden)
+	if err != nil {
+		return err
+	}
+	byDay := map[string][]Case{}
+	for _, c := range cases {
+		byDay[c.Day] = append(byDay[c.Day], c)
+	}
+	days := flags.Args()
+	if len(days) == 0 {
+		for day := range byDay {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+	}
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+
+	failed := false
+	for _, day := range days {
+		dayCases, ok := byDay[day]
+		if !ok {
+			return fmt.Errorf("no golden cases for %s", day)
+		}
+		passed, err := CoverDay(filepath.Join(*root, day), day, dayCases, *outDir, *mutate, *timeout, stdout)
+		if err != nil {
+			return err
+		}
+		failed = failed || !passed
+	}
+	if failed {
+		return errors.New("some golden answers are missing")
+	}
+	return nil
+}
+
+/*
+the coverage and mutation passes of a single day, run on a copy of its
+directory. returns false if any golden answer was missing.
+*/
+func CoverDay(dayDir string, day string, cases []Case, outDir string, mutate bool, timeout time.Duration, stdout io.Writer) (bool, error) {
+	dir, err := CopyDay(dayDir)
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+
+	summary, missing, err := Coverage(dir, day, cases, outDir, timeout)
+	if err != nil {
+		return false, err
+	}
+	status := "ok"
+	if len(missing) > 0 {
+		status = "FAIL"
+	}
+	fmt.Fprintf(stdout, "%s\t%s\tcoverage %s\t%s\n", day, status, summary, filepath.Join(outDir, day+".html"))
+	for _, m := range missing {
+		fmt.Fprintf(stdout, "\tmissing %s\n", m)
+	}
+	// mutants are only meaningful when the unmodified day passes
+	if !mutate || len(missing) > 0 {
+		return len(missing) == 0, nil
+	}
+
+	mutants, err := FindMutants(dir)
+	if err != nil {
+		return false, err
+	}
+	survivors := []Mutant{}
+	for _, m := range mutants {
+		killed, err := TryMutant(dir, m, cases, timeout)
+		if err != nil {
+			return false, err
+		}
+		if !killed {
+			survivors = append(survivors, m)
+		}
+	}
+	fmt.Fprintf(stdout, "\tmutants killed %d/%d\n", len(mutants)-len(survivors), len(mutants))
+	for _, m := range survivors {
+		fmt.Fprintf(stdout, "\tsurvived %s/%s\n", day, m)
+	}
+	return true, nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: aoc coverage [-root dir] [-out dir] [-mutate] [-timeout d] [day_01 ...]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "coverage":
+		Fatal(CoverageCommand(os.Args[2:], os.Stdout))
+	default:
+		usage()
+	}
+}
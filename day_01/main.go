@@ -1,13 +1,28 @@
 package main
 
 import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
 )
 
+var (
+	top     = flag.Int("top", 3, "how many of the best stocked elves to rank, part 2 sums their calories")
+	report  = flag.String("report", "", "print inventory statistics as csv or json instead of the answers")
+	buckets = flag.Int("buckets", 10, "number of histogram buckets in the report")
+	lenient = flag.Bool("lenient", false, "skip malformed ration lines with a warning instead of stopping at the first one")
+)
+
 func Fatal(err error, msg string) {
 	if err != nil {
 		panic(msg)
@@ -16,18 +31,84 @@ func Fatal(err error, msg string) {
 
 type Backpack struct {
 	rations []int
+	lines   int // non-blank lines read, skipped ones included
+}
+
+// a ration line that could not be parsed, Line counts from 1 and Content is
+// the line as it was in the input without its line ending
+type LineError struct {
+	Line    int
+	Content string
+	Err     error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %q: %v", e.Line, e.Content, e.Err)
 }
 
-func (b *Backpack) FromLines(lines []string) []string {
-	for i, line := range lines {
+/*
+the inventory is read line by line. surrounding whitespace, including the
+\r of CRLF line endings, is ignored, so a line holding only whitespace is
+blank. any run of blank lines separates two elves, blank lines at the
+start or end of the input separate nothing.
+in strict mode the first malformed line stops the reading with a LineError,
+in lenient mode it is remembered in Skipped and the elf keeps the rest of
+its rations.
+*/
+type Inventory struct {
+	scanner *bufio.Scanner
+	line    int
+	lenient bool
+	Skipped []LineError
+}
+
+func NewInventory(r io.Reader, lenient bool) *Inventory {
+	return &Inventory{scanner: bufio.NewScanner(r), lenient: lenient}
+}
+
+func ParseRation(line string) (int, error) {
+	calories, err := strconv.Atoi(line)
+	if err != nil {
+		return 0, errors.New("not an integer")
+	}
+	if calories < 0 {
+		return 0, errors.New("negative calories")
+	}
+	return calories, nil
+}
+
+/*
+read rations from the inventory until a blank line or the end of input.
+returns false when the input has run out.
+*/
+func (b *Backpack) FromInventory(in *Inventory) (bool, error) {
+	for in.scanner.Scan() {
+		in.line++
+		raw := strings.TrimSuffix(in.scanner.Text(), "\r")
+		line := strings.TrimSpace(raw)
 		if line == "" {
-			return lines[i+1:]
+			if b.lines == 0 {
+				// repeated blank line, keep looking for this elf's first ration
+				continue
+			}
+			return true, nil
+		}
+		b.lines++
+		calories, err := ParseRation(line)
+		if err != nil {
+			lineErr := LineError{in.line, raw, err}
+			if !in.lenient {
+				return false, lineErr
+			}
+			in.Skipped = append(in.Skipped, lineErr)
+			continue
 		}
-		calories, err := strconv.Atoi(line)
-		Fatal(err, "failed to parse integer")
 		b.rations = append(b.rations, calories)
 	}
-	return []string{}
+	if err := in.scanner.Err(); err != nil {
+		return false, fmt.Errorf("line %d: %w", in.line+1, err)
+	}
+	return false, nil
 }
 
 func (b Backpack) CalorieSum() int {
@@ -38,49 +119,309 @@ func (b Backpack) CalorieSum() int {
 	return sum
 }
 
-func PopulateBackpacks(lines []string) []Backpack {
-	var backpacks []Backpack
-	var backpack *Backpack = new(Backpack)
+/*
+read backpacks line by line and hand each one to handle together with the
+index of its elf, counting from 1. only one backpack is held in memory at a
+time. extra blank lines do not make an elf. an elf whose every line was
+skipped keeps its index but is never handled, so the elves after it keep
+the index of their place in the input. the last elf does not need a final
+newline.
+*/
+func ForEachBackpack(in *Inventory, handle func(index int, b Backpack)) error {
+	for index := 1; ; {
+		backpack := new(Backpack)
+		more, err := backpack.FromInventory(in)
+		if err != nil {
+			return err
+		}
+		if backpack.lines > 0 {
+			if len(backpack.rations) > 0 {
+				handle(index, *backpack)
+			}
+			index++
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+type Elf struct {
+	Index    int `json:"index"`
+	Calories int `json:"calories"`
+}
+
+/*
+keeps the k elves carrying the most calories seen so far as a min-heap.
+the weakest of them is at the root, so a new elf only has to beat that one.
+on equal calories the earlier elf ranks higher.
+*/
+type TopElves struct {
+	k     int
+	elves []Elf
+}
+
+func NewTopElves(k int) *TopElves {
+	return &TopElves{k: k}
+}
+
+func (t *TopElves) Len() int { return len(t.elves) }
+
+func (t *TopElves) Less(i, j int) bool {
+	a, b := t.elves[i], t.elves[j]
+	return a.Calories < b.Calories || a.Calories == b.Calories && a.Index > b.Index
+}
+
+func (t *TopElves) Swap(i, j int) { t.elves[i], t.elves[j] = t.elves[j], t.elves[i] }
 
-	for {
-		lines = backpack.FromLines(lines)
-		backpacks = append(backpacks, *backpack)
-		backpack = new(Backpack)
-		if len(lines) == 0 {
-			break
+func (t *TopElves) Push(x any) { t.elves = append(t.elves, x.(Elf)) }
+
+func (t *TopElves) Pop() any {
+	last := t.elves[len(t.elves)-1]
+	t.elves = t.elves[:len(t.elves)-1]
+	return last
+}
+
+/*
+elves are added in index order, so an elf with the same calories as the
+weakest kept one never replaces it
+*/
+func (t *TopElves) Add(elf Elf) {
+	if len(t.elves) < t.k {
+		heap.Push(t, elf)
+		return
+	}
+	if t.k > 0 && elf.Calories > t.elves[0].Calories {
+		t.elves[0] = elf
+		heap.Fix(t, 0)
+	}
+}
+
+// the kept elves, most calories first
+func (t *TopElves) Ranking() []Elf {
+	ranking := make([]Elf, len(t.elves))
+	copy(ranking, t.elves)
+	sort.Slice(ranking, func(i, j int) bool {
+		return ranking[i].Calories > ranking[j].Calories || ranking[i].Calories == ranking[j].Calories && ranking[i].Index < ranking[j].Index
+	})
+	return ranking
+}
+
+/*
+stream all backpacks and return the k elves with the most calories.
+memory use only depends on k, not on the size of the inventory.
+*/
+func RankBackpacks(in *Inventory, k int) ([]Elf, error) {
+	top := NewTopElves(k)
+	err := ForEachBackpack(in, func(index int, b Backpack) {
+		top.Add(Elf{index, b.CalorieSum()})
+	})
+	return top.Ranking(), err
+}
+
+/*
+statistics over a group of integers, eg. the calorie totals of all elves.
+the value at position i belongs to elf i+1.
+percentiles use the nearest rank. outliers are values further than 1.5
+times the interquartile range below the 25th or above the 75th percentile.
+*/
+type Bucket struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Count int `json:"count"`
+}
+
+type Stats struct {
+	Count       int            `json:"count"`
+	Rations     int            `json:"rations"`
+	Sum         int            `json:"sum"`
+	Min         int            `json:"min"`
+	Max         int            `json:"max"`
+	Mean        float64        `json:"mean"`
+	Median      float64        `json:"median"`
+	StdDev      float64        `json:"stddev"`
+	Percentiles map[string]int `json:"percentiles"`
+	Histogram   []Bucket       `json:"histogram"`
+	Outliers    []Elf          `json:"outliers"`
+}
+
+var ReportPercentiles = []int{10, 25, 50, 75, 90, 99}
+
+// the value below which p percent of the sorted values fall
+func Percentile(sorted []int, p int) int {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func Summarize(elves []Elf, rations int, bucketCount int) Stats {
+	values := make([]int, len(elves))
+	for i, elf := range elves {
+		values[i] = elf.Calories
+	}
+	stats := Stats{Count: len(values), Rations: rations, Percentiles: map[string]int{}, Histogram: []Bucket{}, Outliers: []Elf{}}
+	if len(values) == 0 {
+		return stats
+	}
+	sorted := make([]int, len(values))
+	copy(sorted, values)
+	sort.Ints(sorted)
+
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	for _, v := range values {
+		stats.Sum += v
+	}
+	stats.Mean = float64(stats.Sum) / float64(len(values))
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		stats.Median = float64(sorted[middle-1]+sorted[middle]) / 2
+	} else {
+		stats.Median = float64(sorted[middle])
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (float64(v) - stats.Mean) * (float64(v) - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(squares / float64(len(values)))
+	for _, p := range ReportPercentiles {
+		stats.Percentiles[fmt.Sprintf("p%d", p)] = Percentile(sorted, p)
+	}
+
+	// equal width buckets from min to max, the last one includes max.
+	// there are never more buckets than values between min and max, and
+	// fewer when the width does not divide the range
+	if bucketCount < 1 {
+		bucketCount = 1
+	}
+	if span := stats.Max - stats.Min + 1; bucketCount > span {
+		bucketCount = span
+	}
+	width := (stats.Max-stats.Min)/bucketCount + 1
+	for from := stats.Min; from <= stats.Max; from += width {
+		stats.Histogram = append(stats.Histogram, Bucket{from, from + width - 1, 0})
+	}
+	stats.Histogram[len(stats.Histogram)-1].To = stats.Max
+	for _, v := range values {
+		stats.Histogram[(v-stats.Min)/width].Count++
+	}
+
+	q1, q3 := Percentile(sorted, 25), Percentile(sorted, 75)
+	fence := 3 * (q3 - q1) / 2
+	for _, elf := range elves {
+		if elf.Calories < q1-fence || elf.Calories > q3+fence {
+			stats.Outliers = append(stats.Outliers, elf)
 		}
 	}
-	return backpacks
+	return stats
+}
+
+/*
+csv reports are "statistic,value" rows, histogram buckets and outliers
+get a row each after the summary
+*/
+func WriteCSV(w io.Writer, stats Stats) error {
+	out := csv.NewWriter(w)
+	rows := [][]string{
+		{"statistic", "value"},
+		{"count", strconv.Itoa(stats.Count)},
+		{"rations", strconv.Itoa(stats.Rations)},
+		{"sum", strconv.Itoa(stats.Sum)},
+		{"min", strconv.Itoa(stats.Min)},
+		{"max", strconv.Itoa(stats.Max)},
+		{"mean", strconv.FormatFloat(stats.Mean, 'f', 2, 64)},
+		{"median", strconv.FormatFloat(stats.Median, 'f', 1, 64)},
+		{"stddev", strconv.FormatFloat(stats.StdDev, 'f', 2, 64)},
+	}
+	for _, p := range ReportPercentiles {
+		name := fmt.Sprintf("p%d", p)
+		rows = append(rows, []string{name, strconv.Itoa(stats.Percentiles[name])})
+	}
+	for _, b := range stats.Histogram {
+		rows = append(rows, []string{fmt.Sprintf("histogram %d-%d", b.From, b.To), strconv.Itoa(b.Count)})
+	}
+	for _, elf := range stats.Outliers {
+		rows = append(rows, []string{fmt.Sprintf("outlier elf %d", elf.Index), strconv.Itoa(elf.Calories)})
+	}
+	if err := out.WriteAll(rows); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+/*
+open the input file. the path "-" or no path at all reads stdin.
+*/
+func OpenInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
 }
 
 func main() {
-	filePath := os.Args[1]
+	flag.Parse()
 
-	data, err := os.ReadFile(filePath)
+	input, err := OpenInput(flag.Arg(0))
 	Fatal(err, "failed to find input file")
+	defer input.Close()
+	inventory := NewInventory(input, *lenient)
+	checkInventory := func(err error) {
+		for _, skipped := range inventory.Skipped {
+			fmt.Fprintln(os.Stderr, "skipped", skipped)
+		}
+		if err != nil {
+			panic("invalid inventory: " + err.Error())
+		}
+	}
 
-	lines := strings.Split(string(data), "\n")
+	if *report != "" {
+		// the totals have to be kept for the median and percentiles
+		totals := []Elf{}
+		rations := 0
+		err := ForEachBackpack(inventory, func(index int, b Backpack) {
+			totals = append(totals, Elf{index, b.CalorieSum()})
+			rations += len(b.rations)
+		})
+		checkInventory(err)
+		stats := Summarize(totals, rations, *buckets)
+		switch *report {
+		case "csv":
+			Fatal(WriteCSV(os.Stdout, stats), "failed to write csv report")
+		case "json":
+			data, err := json.MarshalIndent(stats, "", "  ")
+			Fatal(err, "failed to encode json report")
+			fmt.Println(string(data))
+		default:
+			panic("unknown report format: " + *report)
+		}
+		return
+	}
 
-	backpacks := PopulateBackpacks(lines)
+	if *top < 1 {
+		panic("-top must be at least 1")
+	}
+	ranking, err := RankBackpacks(inventory, *top)
+	checkInventory(err)
+	if len(ranking) == 0 {
+		panic("no backpacks in input")
+	}
 
 	// Part 1
-	var highest int = 0
-	for _, bp := range backpacks {
-		if calories := bp.CalorieSum(); calories > highest {
-			highest = calories
-		}
-	}
-	fmt.Println("Part1:", highest)
+	fmt.Println("Part1:", ranking[0].Calories)
 
-	// Part 2
-	var calories []int
-	for _, bp := range backpacks {
-		calories = append(calories, bp.CalorieSum())
+	// Part 2, the top 3 unless -top says otherwise
+	var topSum int = 0
+	elves := []string{}
+	for _, elf := range ranking {
+		topSum = topSum + elf.Calories
+		elves = append(elves, fmt.Sprintf("elf %d (%d)", elf.Index, elf.Calories))
 	}
-	sort.Sort(sort.Reverse(sort.IntSlice(calories)))
-	var top3Sum int = 0
-	for _, cal := range calories[0:3] {
-		top3Sum = top3Sum + cal
+	fmt.Println("Part2:", topSum)
+	if len(elves) < *top {
+		fmt.Fprintf(os.Stderr, "only %d elves for -top %d\n", len(elves), *top)
 	}
-	fmt.Println("Part2:", top3Sum)
+	fmt.Printf("Top%d: %s\n", len(elves), strings.Join(elves, ", "))
 }
@@ -1,17 +1,42 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+var (
+	rulesPath = flag.String("rules", "", "game definition file, plain rock paper scissors when empty")
+	opponent  = flag.String("opponent", "", "instead of the answers, score the guide against a random elf learned from it: frequency or markov")
+	tourney   = flag.Bool("tournament", false, "play every guide given as an argument against every other one and print the standings")
+	part      = flag.Int("part", 1, "in a tournament, read the guides the way part 1 or part 2 does")
+)
+
 func Fatal(err error, msg string) {
 	if err != nil {
 		panic(msg)
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type BattleResult int
 
 const (
@@ -20,89 +45,213 @@ const (
 	Draw
 )
 
+var resultNames = map[string]BattleResult{"win": Win, "lose": Lose, "draw": Draw}
+
 /*
-	Part 1
+a game is defined by a small text file, one statement per line, # starts a
+comment:
+
+	choice <name> <elf symbol> <my symbol> <points>
+	beats <name> <name>
+	outcome win|draw|lose <points>
+	goal <my symbol> win|draw|lose
+
+choices that do not beat each other either way are a draw. goals are how
+part 2 reads my column, a game without them only has part 1.
 */
+const DefaultRules = `
+choice Rock     A X 1
+choice Paper    B Y 2
+choice Scissors C Z 3
+
+beats Rock Scissors
+beats Paper Rock
+beats Scissors Paper
 
-func DoBattle(a string, b string) BattleResult {
-	switch a {
-	case "A": // Rock
-		switch b {
-		case "Z": // Scissors
-			return Lose
-		case "Y": // Paper
-			return Win
-		}
-	case "B": // Paper
-		switch b {
-		case "X": // Rock
-			return Lose
-		case "Z": // Paper
-			return Win
-		}
-	case "C": // Scissors
-		switch b {
-		case "Y": // Paper
-			return Lose
-		case "X": // Rock
-			return Win
+outcome win 6
+outcome draw 3
+outcome lose 0
+
+goal X lose
+goal Y draw
+goal Z win
+`
+
+type Choice struct {
+	Name   string
+	Elf    string
+	Me     string
+	Points int
+}
+
+type Rules struct {
+	Choices []Choice
+	byName  map[string]int
+	byElf   map[string]int
+	byMe    map[string]int
+	beats   map[[2]int]bool
+	Outcome map[BattleResult]int
+	Goals   map[string]BattleResult
+}
+
+func ParseRules(r io.Reader) (*Rules, error) {
+	rules := &Rules{
+		byName:  map[string]int{},
+		byElf:   map[string]int{},
+		byMe:    map[string]int{},
+		beats:   map[[2]int]bool{},
+		Outcome: map[BattleResult]int{},
+		Goals:   map[string]BattleResult{},
+	}
+	scanner := bufio.NewScanner(r)
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
 		}
-	default:
+		if err := rules.statement(fields); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNr, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules.Choices) == 0 {
+		return nil, errors.New("no choices defined")
+	}
+	for name, result := range resultNames {
+		if _, ok := rules.Outcome[result]; !ok {
+			return nil, fmt.Errorf("no points for outcome %s", name)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rules) statement(fields []string) error {
+	arguments := map[string]int{"choice": 5, "beats": 3, "outcome": 3, "goal": 3}
+	want, ok := arguments[fields[0]]
+	if !ok {
+		return fmt.Errorf("unknown statement %q", fields[0])
+	}
+	if len(fields) != want {
+		return fmt.Errorf("%s takes %d arguments, got %d", fields[0], want-1, len(fields)-1)
+	}
+
+	switch fields[0] {
+	case "choice":
+		name, elf, me := fields[1], fields[2], fields[3]
+		points, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return fmt.Errorf("bad points %q for %s", fields[4], name)
+		}
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("choice %s defined twice", name)
+		}
+		if _, ok := r.byElf[elf]; ok {
+			return fmt.Errorf("elf symbol %s used twice", elf)
+		}
+		if _, ok := r.byMe[me]; ok {
+			return fmt.Errorf("my symbol %s used twice", me)
+		}
+		r.byName[name] = len(r.Choices)
+		r.byElf[elf] = len(r.Choices)
+		r.byMe[me] = len(r.Choices)
+		r.Choices = append(r.Choices, Choice{name, elf, me, points})
+	case "beats":
+		winner, ok := r.byName[fields[1]]
+		if !ok {
+			return fmt.Errorf("unknown choice %s", fields[1])
+		}
+		loser, ok := r.byName[fields[2]]
+		if !ok {
+			return fmt.Errorf("unknown choice %s", fields[2])
+		}
+		if winner == loser {
+			return fmt.Errorf("%s can not beat itself", fields[1])
+		}
+		if r.beats[[2]int{loser, winner}] {
+			return fmt.Errorf("%s already beats %s", fields[2], fields[1])
+		}
+		r.beats[[2]int{winner, loser}] = true
+	case "outcome":
+		result, ok := resultNames[fields[1]]
+		if !ok {
+			return fmt.Errorf("unknown outcome %q", fields[1])
+		}
+		points, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("bad points %q for %s", fields[2], fields[1])
+		}
+		r.Outcome[result] = points
+	case "goal":
+		result, ok := resultNames[fields[2]]
+		if !ok {
+			return fmt.Errorf("unknown outcome %q", fields[2])
+		}
+		r.Goals[fields[1]] = result
+	}
+	return nil
+}
+
+/*
+	Part 1
+*/
+
+// the result from my side, a is the elf's symbol and b mine
+func (r *Rules) DoBattle(a string, b string) BattleResult {
+	elf, ok := r.byElf[a]
+	if !ok {
+		panic("bad battle: " + a + " vs " + b)
+	}
+	me, ok := r.byMe[b]
+	if !ok {
 		panic("bad battle: " + a + " vs " + b)
 	}
+	switch {
+	case r.beats[[2]int{me, elf}]:
+		return Win
+	case r.beats[[2]int{elf, me}]:
+		return Lose
+	}
 	return Draw
 }
 
-func PointsForChoice(myChoice string) int {
-	switch myChoice {
-	case "X":
-		return 1
-	case "Y":
-		return 2
-	case "Z":
-		return 3
-	default:
+func (r *Rules) PointsForChoice(myChoice string) int {
+	choice, ok := r.byMe[myChoice]
+	if !ok {
 		panic("bad choice: " + myChoice)
 	}
+	return r.Choices[choice].Points
 }
 
-func GetBattleScore(me string, result BattleResult) int {
-	var score int = 0
-	switch result {
-	case Win:
-		score += 6
-	case Draw:
-		score += 3
-	case Lose:
-	}
-	score += PointsForChoice(me)
-	return score
+func (r *Rules) GetBattleScore(me string, result BattleResult) int {
+	return r.Outcome[result] + r.PointsForChoice(me)
 }
-func P1GetChoices(line string) (string, string) {
+
+func (r *Rules) P1GetChoices(line string) (string, string) {
 	elf, me := ParseLine(line)
 	return elf, me
 }
 
-func P2GetChoices(line string) (string, string) {
-	// me: x = lose, y = draw, z = win
-	var needTo BattleResult
+/*
+my column is the result i need, the first choice in definition order that
+gets it is played
+*/
+func (r *Rules) P2GetChoices(line string) (string, string) {
 	elf, myGoal := ParseLine(line)
-	switch myGoal {
-	case "X":
-		needTo = Lose
-	case "Y":
-		needTo = Draw
-	case "Z":
-		needTo = Win
+	needTo, ok := r.Goals[myGoal]
+	if !ok {
+		panic("bad goal: " + myGoal)
 	}
 
-	for _, choice := range []string{"X", "Y", "Z"} {
-		result := DoBattle(elf, choice)
+	for _, choice := range r.Choices {
+		result := r.DoBattle(elf, choice.Me)
 		if result == needTo {
-			return elf, choice
+			return elf, choice.Me
 		}
 	}
-	panic("bad")
+	panic("no choice reaches goal " + myGoal + " against " + elf)
 }
 
 func ParseLine(line string) (string, string) {
@@ -111,32 +260,413 @@ func ParseLine(line string) (string, string) {
 	return elf, me
 }
 
-func main() {
-	f, err := os.ReadFile(os.Args[1])
+/*
+	Expected scores against a random elf
+*/
+
+/*
+the elf's moves as a markov chain over the choices. Start is the
+distribution of the first move and Next[a][b] the chance that b follows a.
+a frequency model is the chain where every row of Next equals Start.
+*/
+type Opponent struct {
+	Start []float64
+	Next  [][]float64
+}
+
+func (r *Rules) elfMoves(lines []string) []int {
+	moves := []int{}
+	for _, line := range lines {
+		elf, _ := ParseLine(line)
+		move, ok := r.byElf[elf]
+		if !ok {
+			panic("bad elf move: " + elf)
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+func normalize(counts []float64) []float64 {
+	total := 0.0
+	for _, c := range counts {
+		total += c
+	}
+	for i := range counts {
+		counts[i] /= total
+	}
+	return counts
+}
+
+// how often the elf played each choice in the guide
+func (r *Rules) LearnFrequency(lines []string) Opponent {
+	counts := make([]float64, len(r.Choices))
+	for _, move := range r.elfMoves(lines) {
+		counts[move]++
+	}
+	start := normalize(counts)
+	next := make([][]float64, len(r.Choices))
+	for i := range next {
+		next[i] = start
+	}
+	return Opponent{start, next}
+}
+
+/*
+how often each choice followed each other choice in the guide. a choice
+that is never followed by anything uses the plain frequencies.
+*/
+func (r *Rules) LearnMarkov(lines []string) Opponent {
+	frequency := r.LearnFrequency(lines)
+	moves := r.elfMoves(lines)
+	next := make([][]float64, len(r.Choices))
+	for i := range next {
+		next[i] = make([]float64, len(r.Choices))
+	}
+	for i := 1; i < len(moves); i++ {
+		next[moves[i-1]][moves[i]]++
+	}
+	for i, row := range next {
+		seen := false
+		for _, c := range row {
+			seen = seen || c > 0
+		}
+		if seen {
+			next[i] = normalize(row)
+		} else {
+			next[i] = frequency.Start
+		}
+	}
+	return Opponent{frequency.Start, next}
+}
+
+/*
+mine holds my symbol for every round. for each choice y of the elf the
+forward pass keeps the chance that the elf is at y, and the expected total
+score and squared total score up to this round over the paths ending at y.
+*/
+func (r *Rules) ExpectedScore(opp Opponent, mine []string) (mean float64, variance float64) {
+	n := len(r.Choices)
+	prob, sum, square := make([]float64, n), make([]float64, n), make([]float64, n)
+	for round, me := range mine {
+		nextProb, nextSum, nextSquare := make([]float64, n), make([]float64, n), make([]float64, n)
+		for y := 0; y < n; y++ {
+			s := float64(r.GetBattleScore(me, r.DoBattle(r.Choices[y].Elf, me)))
+			if round == 0 {
+				nextProb[y] = opp.Start[y]
+				nextSum[y] = opp.Start[y] * s
+				nextSquare[y] = opp.Start[y] * s * s
+				continue
+			}
+			for x := 0; x < n; x++ {
+				t := opp.Next[x][y]
+				nextProb[y] += t * prob[x]
+				nextSum[y] += t * (sum[x] + s*prob[x])
+				nextSquare[y] += t * (square[x] + 2*s*sum[x] + s*s*prob[x])
+			}
+		}
+		prob, sum, square = nextProb, nextSum, nextSquare
+	}
+	var meanSquare float64
+	for y := 0; y < n; y++ {
+		mean += sum[y]
+		meanSquare += square[y]
+	}
+	return mean, meanSquare - mean*mean
+}
+
+/*
+my symbols for every line of the guide after renaming my column with
+mapping and reading it as part 1 or part 2 does
+*/
+func (r *Rules) MyMoves(lines []string, part int, mapping map[string]string) []string {
+	mine := []string{}
+	for _, line := range lines {
+		elf, column := ParseLine(line)
+		if renamed, ok := mapping[column]; ok {
+			column = renamed
+		}
+		var me string
+		if part == 1 {
+			_, me = r.P1GetChoices(elf + " " + column)
+		} else {
+			_, me = r.P2GetChoices(elf + " " + column)
+		}
+		mine = append(mine, me)
+	}
+	return mine
+}
+
+// every ordering of symbols, starting with the given one
+func Permutations(symbols []string) [][]string {
+	if len(symbols) <= 1 {
+		return [][]string{append([]string{}, symbols...)}
+	}
+	result := [][]string{}
+	for i := range symbols {
+		rest := append(append([]string{}, symbols[:i]...), symbols[i+1:]...)
+		for _, p := range Permutations(rest) {
+			result = append(result, append([]string{symbols[i]}, p...))
+		}
+	}
+	return result
+}
+
+type Mapping struct {
+	Rename   map[string]string
+	Mean     float64
+	Variance float64
+}
+
+func (m Mapping) String() string {
+	from := make([]string, 0, len(m.Rename))
+	for symbol := range m.Rename {
+		from = append(from, symbol)
+	}
+	sort.Strings(from)
+	pairs := []string{}
+	for _, symbol := range from {
+		pairs = append(pairs, symbol+"="+m.Rename[symbol])
+	}
+	return strings.Join(pairs, " ")
+}
+
+/*
+try every way of renaming my column onto itself, my choice symbols for
+part 1 and the goal symbols for part 2. on equal expected scores the
+earlier permutation, so the guide as written, wins.
+*/
+func (r *Rules) BestMapping(opp Opponent, lines []string, part int) Mapping {
+	symbols := []string{}
+	if part == 1 {
+		for _, choice := range r.Choices {
+			symbols = append(symbols, choice.Me)
+		}
+	} else {
+		for symbol := range r.Goals {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+	}
+
+	var best Mapping
+	for i, permutation := range Permutations(symbols) {
+		rename := map[string]string{}
+		for j, symbol := range symbols {
+			rename[symbol] = permutation[j]
+		}
+		mean, variance := r.ExpectedScore(opp, r.MyMoves(lines, part, rename))
+		if i == 0 || mean > best.Mean {
+			best = Mapping{rename, mean, variance}
+		}
+	}
+	return best
+}
+
+func (r *Rules) Analyze(lines []string, model string) {
+	var opp Opponent
+	switch model {
+	case "frequency":
+		opp = r.LearnFrequency(lines)
+	case "markov":
+		opp = r.LearnMarkov(lines)
+	default:
+		panic("unknown opponent model: " + model)
+	}
+
+	parts := []int{1}
+	if len(r.Goals) > 0 {
+		parts = append(parts, 2)
+	}
+	for _, part := range parts {
+		mean, variance := r.ExpectedScore(opp, r.MyMoves(lines, part, nil))
+		fmt.Printf("Part%d: expected %.2f variance %.2f stddev %.2f\n", part, mean, variance, math.Sqrt(variance))
+		best := r.BestMapping(opp, lines, part)
+		fmt.Printf("Part%d best mapping: %s expected %.2f variance %.2f\n", part, best, best.Mean, best.Variance)
+	}
+}
+
+/*
+	Tournament between strategy guides
+*/
+
+type Guide struct {
+	Name  string
+	Lines []string
+}
+
+type Standing struct {
+	Guide  string
+	Played int
+	Won    int
+	Drawn  int
+	Lost   int
+	Points int
+}
+
+/*
+the symbol of my choice in every round of the guide, the second column
+read as part 1 or part 2 does
+*/
+func (r *Rules) GuideMoves(guide Guide, part int) []string {
+	moves := []string{}
+	for _, line := range guide.Lines {
+		var me string
+		if part == 1 {
+			_, me = r.P1GetChoices(line)
+		} else {
+			_, me = r.P2GetChoices(line)
+		}
+		moves = append(moves, me)
+	}
+	return moves
+}
+
+/*
+a match lasts as long as the shorter of the two guides. both sides score
+their rounds with GetBattleScore and the side with more points wins.
+*/
+func (r *Rules) Match(a []string, b []string) (int, int) {
+	var scoreA, scoreB int
+	for round := 0; round < len(a) && round < len(b); round++ {
+		// DoBattle wants the other side as the elf
+		elfA := r.Choices[r.byMe[a[round]]].Elf
+		elfB := r.Choices[r.byMe[b[round]]].Elf
+		scoreA += r.GetBattleScore(a[round], r.DoBattle(elfB, a[round]))
+		scoreB += r.GetBattleScore(b[round], r.DoBattle(elfA, b[round]))
+	}
+	return scoreA, scoreB
+}
+
+/*
+round robin, every guide meets every other guide once. the standings are
+sorted by wins, then by points, then by name.
+*/
+func (r *Rules) Tournament(guides []Guide, part int) []Standing {
+	moves := make([][]string, len(guides))
+	standings := make([]Standing, len(guides))
+	for i, guide := range guides {
+		moves[i] = r.GuideMoves(guide, part)
+		standings[i].Guide = guide.Name
+	}
+
+	for i := range guides {
+		for j := i + 1; j < len(guides); j++ {
+			a, b := &standings[i], &standings[j]
+			scoreA, scoreB := r.Match(moves[i], moves[j])
+			a.Played, b.Played = a.Played+1, b.Played+1
+			a.Points, b.Points = a.Points+scoreA, b.Points+scoreB
+			switch {
+			case scoreA > scoreB:
+				a.Won, b.Lost = a.Won+1, b.Lost+1
+			case scoreA < scoreB:
+				a.Lost, b.Won = a.Lost+1, b.Won+1
+			default:
+				a.Drawn, b.Drawn = a.Drawn+1, b.Drawn+1
+			}
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Won != b.Won {
+			return a.Won > b.Won
+		}
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Guide < b.Guide
+	})
+	return standings
+}
+
+func PrintStandings(standings []Standing) {
+	width := len("Guide")
+	for _, s := range standings {
+		if len(s.Guide) > width {
+			width = len(s.Guide)
+		}
+	}
+	fmt.Printf("%4s  %-*s %6s %4s %5s %4s %7s\n", "Rank", width, "Guide", "Played", "Won", "Drawn", "Lost", "Points")
+	for i, s := range standings {
+		fmt.Printf("%4d  %-*s %6d %4d %5d %4d %7d\n", i+1, width, s.Guide, s.Played, s.Won, s.Drawn, s.Lost, s.Points)
+	}
+}
+
+func ReadGuide(path string) []string {
+	f, err := ReadInput(path)
 	Fatal(err, "failed to open file")
 	data := string(f)
 	var lines []string = strings.Split(data, "\n")
-	lines = lines[:len(lines)-1] // remove last empty string
+	return lines[:len(lines)-1] // remove last empty string
+}
+
+func LoadRules(path string) (*Rules, error) {
+	if path == "" {
+		return ParseRules(strings.NewReader(DefaultRules))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRules(f)
+}
+
+func main() {
+	flag.Parse()
+	rules, err := LoadRules(*rulesPath)
+	if err != nil {
+		panic("bad rules: " + err.Error())
+	}
+
+	if *tourney {
+		if flag.NArg() < 2 {
+			panic("a tournament needs at least two guides")
+		}
+		if *part != 1 && *part != 2 {
+			panic("-part must be 1 or 2")
+		}
+		if *part == 2 && len(rules.Goals) == 0 {
+			panic("the rules have no goals for part 2")
+		}
+		guides := []Guide{}
+		for _, path := range flag.Args() {
+			guides = append(guides, Guide{filepath.Base(path), ReadGuide(path)})
+		}
+		PrintStandings(rules.Tournament(guides, *part))
+		return
+	}
+
+	lines := ReadGuide(flag.Arg(0))
+
+	if *opponent != "" {
+		rules.Analyze(lines, *opponent)
+		return
+	}
 
 	/*
 		Part 1
 	*/
 	var score int = 0
 	for _, line := range lines {
-		elf, me := P1GetChoices(line)
-		result := DoBattle(elf, me)
-		score += GetBattleScore(me, result)
+		elf, me := rules.P1GetChoices(line)
+		result := rules.DoBattle(elf, me)
+		score += rules.GetBattleScore(me, result)
 	}
 	fmt.Println("Part1:", score)
 
 	/*
 		Part 2
 	*/
+	if len(rules.Goals) == 0 {
+		return
+	}
 	score = 0
 	for _, line := range lines {
-		elf, me := P2GetChoices(line)
-		result := DoBattle(elf, me)
-		score += GetBattleScore(me, result)
+		elf, me := rules.P2GetChoices(line)
+		result := rules.DoBattle(elf, me)
+		score += rules.GetBattleScore(me, result)
 	}
 	fmt.Println("Part2:", score)
 }
@@ -1,157 +1,360 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math/bits"
 	"os"
+	"strconv"
 	"strings"
+	"unicode/utf8"
+)
+
+var (
+	groupSize  = flag.Int("group-size", 3, "number of rucksacks in a group that shares a badge")
+	tablePath  = flag.String("priorities", "", "item priority table, a-z=1..26 and A-Z=27..52 when empty")
+	reportMode = flag.Bool("report", false, "list the shared items of every rucksack and group instead of the answers")
 )
 
 /*
-This function reads a line and splits it in half.
-It then finds a common character on both sides of the line.
-There is only 1 common character per line.
-Each common lowercase character gets a score of 1-26 for a-z.
-Each uppercase character gets a score of 27-52 for A-Z.
+a priority table file has one entry per line, # starts a comment.
+an entry is an item or a first-last range of items followed by the
+priority of its first item, the following items of a range count up:
+
+	a-z 1
+	A-Z 27
+	ä 53
 */
-func LineScorer(line string) int {
-	// Split the line in half
-	half := len(line) / 2
-	left := line[:half]
-	right := line[half:]
-
-	// Find the common character
-	var common rune
-	for _, l := range left {
-		for _, r := range right {
-			if l == r {
-				common = l
-				break
-			}
+const DefaultPriorities = `
+a-z 1
+A-Z 27
+`
+
+/*
+the items a table knows. bit i of an ItemSet stands for items[i], any
+number of items fit.
+*/
+type Priorities struct {
+	items    []rune
+	priority []int
+	bit      map[rune]int
+}
+
+func ParsePriorities(r io.Reader) (*Priorities, error) {
+	table := &Priorities{bit: map[rune]int{}}
+	scanner := bufio.NewScanner(r)
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
 		}
+		if err := table.entry(fields); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNr, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(table.items) == 0 {
+		return nil, errors.New("no items in priority table")
 	}
+	return table, nil
+}
 
-	// Calculate the score
-	var score int
-	switch {
-	case common >= 'a' && common <= 'z':
-		score = int(common-'a') + 1
-	case common >= 'A' && common <= 'Z':
-		score = int(common-'A') + 27
+func (t *Priorities) entry(fields []string) error {
+	if len(fields) != 2 {
+		return fmt.Errorf("want an item or range and a priority, got %q", strings.Join(fields, " "))
+	}
+	priority, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("bad priority %q", fields[1])
 	}
 
-	return score
+	first, size := utf8.DecodeRuneInString(fields[0])
+	last := first
+	if rest := fields[0][size:]; rest != "" {
+		// a range, first-last
+		var ok bool
+		last, ok = parseRangeEnd(rest)
+		if !ok || last < first {
+			return fmt.Errorf("bad item or range %q", fields[0])
+		}
+	}
+	for item := first; item <= last; item++ {
+		if _, ok := t.bit[item]; ok {
+			return fmt.Errorf("item %q listed twice", item)
+		}
+		t.bit[item] = len(t.items)
+		t.items = append(t.items, item)
+		t.priority = append(t.priority, priority+int(item-first))
+	}
+	return nil
 }
 
-func Fatal(err error, msg string) {
+// "-z" gives 'z'
+func parseRangeEnd(s string) (rune, bool) {
+	if !strings.HasPrefix(s, "-") {
+		return 0, false
+	}
+	last, size := utf8.DecodeRuneInString(s[1:])
+	return last, size > 0 && len(s) == 1+size
+}
+
+func LoadPriorities(path string) (*Priorities, error) {
+	if path == "" {
+		return ParsePriorities(strings.NewReader(DefaultPriorities))
+	}
+	f, err := os.Open(path)
 	if err != nil {
-		fmt.Println(msg, err)
-		os.Exit(1)
+		return nil, err
 	}
+	defer f.Close()
+	return ParsePriorities(f)
 }
 
 /*
-count characters in each item separately.
-then count how many maps contain the character.
-return characters that are present in all maps
-do not use indexing
+a set of items of a priority table as a bitmask, 64 items to a word.
+the default table needs only one word.
 */
-func Intersection(items []string) string {
-	// Count characters in each item
-	var maps []map[rune]int
+type ItemSet []uint64
+
+func (t *Priorities) NewItemSet(items []rune) (ItemSet, error) {
+	set := make(ItemSet, (len(t.items)+63)/64)
 	for _, item := range items {
-		m := make(map[rune]int)
-		for _, c := range item {
-			m[c]++
+		bit, ok := t.bit[item]
+		if !ok {
+			return nil, fmt.Errorf("unknown item %q", item)
 		}
-		maps = append(maps, m)
+		set[bit/64] |= 1 << (bit % 64)
 	}
+	return set, nil
+}
 
-	// Count how many maps contain the character
-	var count map[rune]int = make(map[rune]int)
-	for _, m := range maps {
-		for c := range m {
-			if _, ok := count[c]; ok {
-				count[c]++
-			} else {
-				count[c] = 1
-			}
-		}
+// the items in both sets, which come from the same table
+func (s ItemSet) Intersect(o ItemSet) ItemSet {
+	both := make(ItemSet, len(s))
+	for i := range s {
+		both[i] = s[i] & o[i]
 	}
+	return both
+}
 
-	// Return characters that are present in all maps
-	var result string
-	for c, n := range count {
-		if n == len(maps) {
-			result += string(c)
+func (s ItemSet) Count() int {
+	count := 0
+	for _, word := range s {
+		count += bits.OnesCount64(word)
+	}
+	return count
+}
+
+// call visit with the position in the table of every item in the set
+func (s ItemSet) each(visit func(bit int)) {
+	for i, word := range s {
+		for rest := word; rest != 0; rest &= rest - 1 {
+			visit(i*64 + bits.TrailingZeros64(rest))
 		}
 	}
-	return result
+}
+
+// the items of the set in table order
+func (t *Priorities) Items(s ItemSet) []rune {
+	var items []rune
+	s.each(func(bit int) { items = append(items, t.items[bit]) })
+	return items
+}
+
+// the sum of the priorities of all items in the set
+func (t *Priorities) Score(s ItemSet) int {
+	var total int
+	s.each(func(bit int) { total += t.priority[bit] })
+	return total
 }
 
 /*
-Read lines into groups of 3 lines.
-Find the intersection of the 3 lines.
-The intersection is the group key.
+This function reads a line and splits it in half.
+It then finds the items common to both sides of the line.
+There should be only 1 common item per line.
+Items are counted as runes, not bytes.
+*/
+func (t *Priorities) Shared(line string) (ItemSet, error) {
+	items := []rune(line)
+	if len(items)%2 != 0 {
+		return nil, fmt.Errorf("odd number of items, %d", len(items))
+	}
+	half := len(items) / 2
+	left, err := t.NewItemSet(items[:half])
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.NewItemSet(items[half:])
+	if err != nil {
+		return nil, err
+	}
+	return left.Intersect(right), nil
+}
 
-Example input:
+// the score of a line is the priority of its shared items
+func (t *Priorities) LineScorer(line string) (int, error) {
+	shared, err := t.Shared(line)
+	return t.Score(shared), err
+}
+
+func Fatal(err error, msg string) {
+	if err != nil {
+		fmt.Println(msg, err)
+		os.Exit(1)
+	}
+}
+
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+/*
+Read lines into groups of size lines.
+Find the items common to all lines of a group.
+The common items are the group badge.
+A last group with fewer lines is an error.
+
+Example input with size 3:
 vJrwpWtwJgWrhcsFMMfFFhFp
 jqHRNqRjqzjGDLGLrsFMfFZSrLrFZsSL
 wMqvLMZHhHMvwLHjbvcjnnSBnvTQFn
 ttgJtRGJQctTZtZT
 PmmdzqPrVvPwwTWBwg
 CrZsJsPPZsGzwwsLwLmpwMDw
-Output: []{'r', 'Z'}
+Output: []{'r', 'Z'} as item sets
 */
-func LineGrouper(lines []string) []rune {
-	var groups []rune
-	for i := 0; i < len(lines); i += 3 {
-		groups = append(groups, []rune(Intersection(lines[i:i+3]))...)
+func (t *Priorities) LineGrouper(lines []string, size int) ([]ItemSet, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("group size must be at least 1, not %d", size)
 	}
-	return groups
+	if len(lines)%size != 0 {
+		first := len(lines) - len(lines)%size + 1
+		return nil, fmt.Errorf("incomplete group: rucksacks %d-%d are only %d of %d", first, len(lines), len(lines)%size, size)
+	}
+	var groups []ItemSet
+	for i := 0; i < len(lines); i += size {
+		var badge ItemSet
+		for j, line := range lines[i : i+size] {
+			set, err := t.NewItemSet([]rune(line))
+			if err != nil {
+				return nil, fmt.Errorf("rucksack %d: %w", i+j+1, err)
+			}
+			if j == 0 {
+				badge = set
+			} else {
+				badge = badge.Intersect(set)
+			}
+		}
+		groups = append(groups, badge)
+	}
+	return groups, nil
 }
 
 /*
-Each line is fed into LineGrouper to find groups of 3 lines.
-Each group key is then scored a-z=1-26 and A-Z=27-25.
-The scores are calculated without LineScorer, and on the group key.
+Each line is fed into LineGrouper to find groups of size lines.
+Each group badge is then scored by its priority.
 */
-func GroupScorer(lines []string) int {
+func (t *Priorities) GroupScorer(lines []string, size int) (int, error) {
 	var total int
-	groups := LineGrouper(lines)
+	groups, err := t.LineGrouper(lines, size)
+	if err != nil {
+		return 0, err
+	}
 	for _, group := range groups {
-		switch {
-		case group >= 'a' && group <= 'z':
-			total += int(group-'a') + 1
-		case group >= 'A' && group <= 'Z':
-			total += int(group-'A') + 27
-		}
+		total += t.Score(group)
 	}
-	return total
+	return total, nil
 }
 
 /*
-This function reads a file, then calls LineScorer with each line and adds the score to the total.
+This function calls LineScorer with each line and adds the score to the total.
 Return the score.
 The code is compact.
 */
-func FileScorer(filename string) int {
+func (t *Priorities) FileScorer(lines []string) (int, error) {
 	var total int
-	data, err := os.ReadFile(filename)
-	Fatal(err, "Failed to open file")
-	var lines []string = strings.Split(string(data), "\n")
-	for _, line := range lines {
-		total += LineScorer(line)
+	for i, line := range lines {
+		score, err := t.LineScorer(line)
+		if err != nil {
+			return 0, fmt.Errorf("rucksack %d: %w", i+1, err)
+		}
+		total += score
 	}
-	return total
+	return total, nil
+}
+
+/*
+one line per shared item set, every item with its priority.
+a set without exactly one item is flagged.
+*/
+func (t *Priorities) describe(what string, s ItemSet) string {
+	items := []string{}
+	for _, item := range t.Items(s) {
+		items = append(items, fmt.Sprintf("%c(%d)", item, t.priority[t.bit[item]]))
+	}
+	var mark string
+	switch s.Count() {
+	case 0:
+		mark = "  NONE"
+		items = append(items, "-")
+	case 1:
+	default:
+		mark = "  MULTIPLE"
+	}
+	return fmt.Sprintf("%s: %s%s", what, strings.Join(items, " "), mark)
+}
+
+/*
+list the shared items of every rucksack and the badge items of every group.
+returns how many lines were flagged.
+*/
+func (t *Priorities) Report(w io.Writer, lines []string, size int) (int, error) {
+	flagged := 0
+	for i, line := range lines {
+		shared, err := t.Shared(line)
+		if err != nil {
+			return flagged, fmt.Errorf("rucksack %d: %w", i+1, err)
+		}
+		if shared.Count() != 1 {
+			flagged++
+		}
+		fmt.Fprintln(w, t.describe(fmt.Sprintf("rucksack %d", i+1), shared))
+	}
+
+	groups, err := t.LineGrouper(lines, size)
+	if err != nil {
+		return flagged, err
+	}
+	for i, badge := range groups {
+		if badge.Count() != 1 {
+			flagged++
+		}
+		what := fmt.Sprintf("group %d (rucksacks %d-%d)", i+1, i*size+1, (i+1)*size)
+		fmt.Fprintln(w, t.describe(what, badge))
+	}
+	return flagged, nil
 }
 
 /*
 The function ReadFileLines reads a file and returns an array of lines.
+The input is read only once, so it can come from stdin too.
 Remove empty lines.
 Manual!
 */
 func ReadFileLines(filename string) []string {
-	data, err := os.ReadFile(filename)
+	data, err := ReadInput(filename)
 	Fatal(err, "Failed to open file")
 	var lines []string = strings.Split(string(data), "\n")
 	var result []string
@@ -164,6 +367,22 @@ func ReadFileLines(filename string) []string {
 }
 
 func main() {
-	fmt.Println("Part1:", FileScorer(os.Args[1]))
-	fmt.Println("Part2:", GroupScorer(ReadFileLines(os.Args[1])))
+	flag.Parse()
+	table, err := LoadPriorities(*tablePath)
+	Fatal(err, "Bad priority table:")
+	lines := ReadFileLines(flag.Arg(0))
+
+	if *reportMode {
+		flagged, err := table.Report(os.Stdout, lines, *groupSize)
+		Fatal(err, "Bad input:")
+		fmt.Println("Flagged:", flagged)
+		return
+	}
+
+	part1, err := table.FileScorer(lines)
+	Fatal(err, "Bad rucksack:")
+	fmt.Println("Part1:", part1)
+	part2, err := table.GroupScorer(lines, *groupSize)
+	Fatal(err, "Bad group:")
+	fmt.Println("Part2:", part2)
 }
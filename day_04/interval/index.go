@@ -0,0 +1,159 @@
+package interval
+
+import (
+	"container/heap"
+	"sort"
+)
+
+/*
+Index answers questions about many intervals at once. The intervals are
+kept sorted by start as an implicit balanced tree, the middle of every
+slice is the root of its subtree, and each node knows the largest end in
+its subtree. Queries report intervals by their position in the slice the
+index was built from.
+*/
+type Index struct {
+	intervals []Interval
+	order     []int // positions in the original slice, sorted by start
+	maxEnd    []int // largest end in the subtree rooted at each node
+}
+
+func NewIndex(intervals []Interval) *Index {
+	index := &Index{intervals: intervals}
+	for i, in := range intervals {
+		if !in.Empty() {
+			index.order = append(index.order, i)
+		}
+	}
+	sort.SliceStable(index.order, func(a, b int) bool {
+		return intervals[index.order[a]].Start < intervals[index.order[b]].Start
+	})
+	index.maxEnd = make([]int, len(index.order))
+	index.build(0, len(index.order))
+	return index
+}
+
+func (x *Index) build(lo, hi int) int {
+	if lo >= hi {
+		return 0
+	}
+	mid := (lo + hi) / 2
+	end := x.intervals[x.order[mid]].End
+	if lo < mid {
+		end = maxInt(end, x.build(lo, mid))
+	}
+	if mid+1 < hi {
+		end = maxInt(end, x.build(mid+1, hi))
+	}
+	x.maxEnd[mid] = end
+	return end
+}
+
+/*
+the positions of the intervals sharing a section with q, in order of their
+start. it takes O(log n + k) for k results.
+*/
+func (x *Index) Overlapping(q Interval) []int {
+	found := []int{}
+	if q.Empty() {
+		return found
+	}
+	var visit func(lo, hi int)
+	visit = func(lo, hi int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		// nothing in this subtree reaches q
+		if x.maxEnd[mid] <= q.Start {
+			return
+		}
+		visit(lo, mid)
+		in := x.intervals[x.order[mid]]
+		if in.Start >= q.End {
+			// the right subtree starts even later
+			return
+		}
+		if in.Overlaps(q) {
+			found = append(found, x.order[mid])
+		}
+		visit(mid+1, hi)
+	}
+	visit(0, len(x.order))
+	return found
+}
+
+// the positions of the intervals that have the section
+func (x *Index) Covering(section int) []int {
+	return x.Overlapping(Interval{section, section + 1})
+}
+
+/*
+the largest number of intervals that share a section, and the sections
+where that many meet
+*/
+func (x *Index) MaxConcurrent() (int, []Interval) {
+	// ends are sorted too, an interval that ends where another starts
+	// does not meet it
+	ends := make([]int, len(x.order))
+	for i, pos := range x.order {
+		ends[i] = x.intervals[pos].End
+	}
+	sort.Ints(ends)
+
+	best, open, e := 0, 0, 0
+	for _, pos := range x.order {
+		for ; ends[e] <= x.intervals[pos].Start; e++ {
+			open--
+		}
+		open++
+		best = maxInt(best, open)
+	}
+	if best == 0 {
+		return 0, nil
+	}
+	return best, CoveredAtLeast(x.intervals, best)
+}
+
+// two intervals that share at least one section, A before B in start order
+type Overlap struct {
+	A, B int
+}
+
+type byEnd struct {
+	intervals []Interval
+	active    []int
+}
+
+func (h *byEnd) Len() int { return len(h.active) }
+func (h *byEnd) Less(i, j int) bool {
+	return h.intervals[h.active[i]].End < h.intervals[h.active[j]].End
+}
+func (h *byEnd) Swap(i, j int) { h.active[i], h.active[j] = h.active[j], h.active[i] }
+func (h *byEnd) Push(x any)    { h.active = append(h.active, x.(int)) }
+func (h *byEnd) Pop() any {
+	last := h.active[len(h.active)-1]
+	h.active = h.active[:len(h.active)-1]
+	return last
+}
+
+/*
+every pair of overlapping intervals. a sweep in start order keeps the
+intervals that are still open in a heap by end, each new interval overlaps
+exactly the ones left open when it starts. O(n log n + k) for k pairs.
+*/
+func (x *Index) Overlaps() []Overlap {
+	pairs := []Overlap{}
+	open := &byEnd{intervals: x.intervals}
+	for _, pos := range x.order {
+		start := x.intervals[pos].Start
+		for open.Len() > 0 && x.intervals[open.active[0]].End <= start {
+			heap.Pop(open)
+		}
+		for _, other := range open.active {
+			pairs = append(pairs, Overlap{other, pos})
+		}
+		heap.Push(open, pos)
+	}
+	return pairs
+}
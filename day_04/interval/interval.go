@@ -0,0 +1,193 @@
+/*
+Package interval works with ranges of integer sections.
+
+An Interval is stored half-open, [Start, End), which keeps lengths and
+adjacency simple. Closed ranges like the 2-4 of an assignment are made with
+Closed and printed back the same way by String.
+*/
+package interval
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Interval struct {
+	Start, End int
+}
+
+// the sections start to end, both included
+func Closed(start, end int) Interval {
+	return Interval{start, end + 1}
+}
+
+// the sections from start up to but not including end
+func HalfOpen(start, end int) Interval {
+	return Interval{start, end}
+}
+
+// the last section in the interval, for closed ranges
+func (i Interval) Last() int {
+	return i.End - 1
+}
+
+func (i Interval) Empty() bool {
+	return i.End <= i.Start
+}
+
+func (i Interval) Len() int {
+	if i.Empty() {
+		return 0
+	}
+	return i.End - i.Start
+}
+
+func (i Interval) Has(section int) bool {
+	return i.Start <= section && section < i.End
+}
+
+// every section of o is also in i, an empty o is in every interval
+func (i Interval) Contains(o Interval) bool {
+	return o.Empty() || i.Start <= o.Start && o.End <= i.End
+}
+
+// i and o share at least one section
+func (i Interval) Overlaps(o Interval) bool {
+	return !i.Intersect(o).Empty()
+}
+
+// the sections in both, empty when they do not overlap
+func (i Interval) Intersect(o Interval) Interval {
+	return Interval{maxInt(i.Start, o.Start), minInt(i.End, o.End)}
+}
+
+/*
+the sections in either one. only intervals that overlap or touch make a
+single interval, for others ok is false.
+*/
+func (i Interval) Union(o Interval) (Interval, bool) {
+	if i.Empty() {
+		return o, true
+	}
+	if o.Empty() {
+		return i, true
+	}
+	if i.End < o.Start || o.End < i.Start {
+		return Interval{}, false
+	}
+	return Interval{minInt(i.Start, o.Start), maxInt(i.End, o.End)}, true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// closed notation, 2-4
+func (i Interval) String() string {
+	if i.Empty() {
+		return "empty"
+	}
+	return fmt.Sprintf("%d-%d", i.Start, i.Last())
+}
+
+/*
+sort the intervals and join the ones that overlap or touch. the result is
+the same set of sections without any of them listed twice.
+*/
+func Merge(intervals []Interval) []Interval {
+	sorted := []Interval{}
+	for _, i := range intervals {
+		if !i.Empty() {
+			sorted = append(sorted, i)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].Start < sorted[b].Start })
+
+	merged := []Interval{}
+	for _, i := range sorted {
+		if n := len(merged); n > 0 {
+			if union, ok := merged[n-1].Union(i); ok {
+				merged[n-1] = union
+				continue
+			}
+		}
+		merged = append(merged, i)
+	}
+	return merged
+}
+
+// the parts of within that none of the intervals cover
+func Gaps(intervals []Interval, within Interval) []Interval {
+	gaps := []Interval{}
+	at := within.Start
+	for _, i := range Merge(intervals) {
+		if i.End <= at {
+			continue
+		}
+		if i.Start >= within.End {
+			break
+		}
+		if i.Start > at {
+			gaps = append(gaps, Interval{at, i.Start})
+		}
+		at = i.End
+	}
+	if at < within.End {
+		gaps = append(gaps, Interval{at, within.End})
+	}
+	return gaps
+}
+
+// how many sections are covered by at least one of the intervals
+func Coverage(intervals []Interval) int {
+	total := 0
+	for _, i := range Merge(intervals) {
+		total += i.Len()
+	}
+	return total
+}
+
+/*
+the sections covered by at least k of the intervals, found with a sweep
+over the start and end points in O(n log n)
+*/
+func CoveredAtLeast(intervals []Interval, k int) []Interval {
+	type event struct {
+		at    int
+		delta int
+	}
+	events := []event{}
+	for _, i := range intervals {
+		if !i.Empty() {
+			events = append(events, event{i.Start, 1}, event{i.End, -1})
+		}
+	}
+	sort.Slice(events, func(a, b int) bool { return events[a].at < events[b].at })
+
+	covered := []Interval{}
+	depth := 0
+	for n := 0; n < len(events); {
+		at := events[n].at
+		before := depth
+		for ; n < len(events) && events[n].at == at; n++ {
+			depth += events[n].delta
+		}
+		switch {
+		case before < k && depth >= k:
+			covered = append(covered, Interval{at, at})
+		case before >= k && depth < k:
+			covered[len(covered)-1].End = at
+		}
+	}
+	return covered
+}
@@ -1,10 +1,25 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"main/interval"
+)
+
+var (
+	atLeast     = flag.Int("k", 0, "also count the sections that at least k elves are assigned to")
+	coverQuery  = flag.String("cover", "", "also list the assignments that cover this section")
+	concurrent  = flag.Bool("concurrent", false, "also print the largest number of assignments sharing a section")
+	overlapping = flag.Bool("overlapping", false, "also list every pair of overlapping assignments over all lines")
+	reversed    = flag.String("reversed", "reject", "ranges written end first, like 8-2: reject or normalize")
+	strict      = flag.Bool("strict", false, "stop when any line is rejected instead of answering for the rest")
 )
 
 // from signature
@@ -15,39 +30,129 @@ func Fatal(e error) {
 }
 
 /*
-A struct containing two pairs of number ranges. Call them A and B.
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+/*
+The section assignments of one line, usually a pair of elves but any
+number of them works.
 */
 type Pairs struct {
-	A, B [2]int
+	Line        int
+	Assignments []interval.Interval
 }
 
-// Method on Pairs to check if either A contains B or B contains A
+// Method on Pairs to check if one assignment contains another one
 // Takes no arguments
 func (p Pairs) Contains() bool {
-	return p.A[0] <= p.B[0] && p.A[1] >= p.B[1] || p.B[0] <= p.A[0] && p.B[1] >= p.A[1]
+	for i, a := range p.Assignments {
+		for j, b := range p.Assignments {
+			if i != j && a.Contains(b) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// Method on pairs to check if A overlaps B in any place
+// Method on pairs to check if two assignments overlap in any place
 func (p Pairs) Overlaps() bool {
-	return p.A[0] <= p.B[0] && p.A[1] >= p.B[0] || p.B[0] <= p.A[0] && p.B[1] >= p.A[0]
+	for i, a := range p.Assignments {
+		for _, b := range p.Assignments[i+1:] {
+			if a.Overlaps(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+what to do with a range written end first, like 8-2
+*/
+type ReversedPolicy int
+
+const (
+	RejectReversed ReversedPolicy = iota
+	NormalizeReversed
+)
+
+// a line that could not be parsed, Line counts from 1
+type LineError struct {
+	Line    int
+	Content string
+	Err     error
 }
 
-// PairsParser returns a Pairs struct containing the two pairs of numbers.
-func PairsParser(line string) Pairs {
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %q: %v", e.Line, e.Content, e.Err)
+}
+
+// both ends may be negative, -5--2 is the range from -5 to -2
+var rangePattern = regexp.MustCompile(`^(-?\d+)-(-?\d+)$`)
+
+func ParseRange(part string, policy ReversedPolicy) (interval.Interval, error) {
+	match := rangePattern.FindStringSubmatch(strings.TrimSpace(part))
+	if match == nil {
+		return interval.Interval{}, fmt.Errorf("bad range %q, want start-end", part)
+	}
+	start, err := strconv.Atoi(match[1])
+	if err != nil {
+		return interval.Interval{}, fmt.Errorf("bad start in %q: %w", part, err)
+	}
+	end, err := strconv.Atoi(match[2])
+	if err != nil {
+		return interval.Interval{}, fmt.Errorf("bad end in %q: %w", part, err)
+	}
+	if start > end {
+		if policy == RejectReversed {
+			return interval.Interval{}, fmt.Errorf("reversed range %q", part)
+		}
+		start, end = end, start
+	}
+	return interval.Closed(start, end), nil
+}
+
+// PairsParser returns a Pairs struct containing the comma separated ranges.
+func PairsParser(line string, policy ReversedPolicy) (Pairs, error) {
 	var p Pairs
+	if strings.TrimSpace(line) == "" {
+		return p, errors.New("no assignments")
+	}
 	parts := strings.Split(line, ",")
-	for i, part := range parts {
-		nums := strings.Split(part, "-")
-		for j, num := range nums {
-			n, _ := strconv.Atoi(num)
-			if i == 0 {
-				p.A[j] = n
-			} else {
-				p.B[j] = n
-			}
+	for _, part := range parts {
+		section, err := ParseRange(part, policy)
+		if err != nil {
+			return p, err
+		}
+		p.Assignments = append(p.Assignments, section)
+	}
+	return p, nil
+}
+
+/*
+parse every line, the ones that fail are left out of the result and
+returned as errors instead
+*/
+func ParseLines(lines []string, policy ReversedPolicy) ([]Pairs, []LineError) {
+	pairs := []Pairs{}
+	rejected := []LineError{}
+	for i, line := range lines {
+		p, err := PairsParser(line, policy)
+		if err != nil {
+			rejected = append(rejected, LineError{i + 1, line, err})
+			continue
 		}
+		p.Line = i + 1
+		pairs = append(pairs, p)
 	}
-	return p
+	return pairs, rejected
 }
 
 /*
@@ -80,6 +185,72 @@ func Part2(pairs []Pairs) int {
 	return count
 }
 
+/*
+The sections that at least k elves were assigned, over all lines.
+*/
+func CoveredAtLeast(pairs []Pairs, k int) int {
+	all := []interval.Interval{}
+	for _, p := range pairs {
+		all = append(all, p.Assignments...)
+	}
+	return interval.Coverage(interval.CoveredAtLeast(all, k))
+}
+
+/*
+Where an assignment came from, both counted from 1.
+*/
+type Assignment struct {
+	Line     int
+	Elf      int
+	Sections interval.Interval
+}
+
+func (a Assignment) String() string {
+	return fmt.Sprintf("line %d elf %d (%s)", a.Line, a.Elf, a.Sections)
+}
+
+/*
+All assignments of all lines in one interval index, so questions across
+lines do not need to compare every pair.
+*/
+type Assignments struct {
+	all   []Assignment
+	index *interval.Index
+}
+
+func IndexPairs(pairs []Pairs) Assignments {
+	var a Assignments
+	sections := []interval.Interval{}
+	for _, p := range pairs {
+		for j, s := range p.Assignments {
+			a.all = append(a.all, Assignment{p.Line, j + 1, s})
+			sections = append(sections, s)
+		}
+	}
+	a.index = interval.NewIndex(sections)
+	return a
+}
+
+func (a Assignments) Covering(section int) []Assignment {
+	found := []Assignment{}
+	for _, i := range a.index.Covering(section) {
+		found = append(found, a.all[i])
+	}
+	return found
+}
+
+func (a Assignments) MaxConcurrent() (int, []interval.Interval) {
+	return a.index.MaxConcurrent()
+}
+
+func (a Assignments) Overlaps() [][2]Assignment {
+	found := [][2]Assignment{}
+	for _, o := range a.index.Overlaps() {
+		found = append(found, [2]Assignment{a.all[o.A], a.all[o.B]})
+	}
+	return found
+}
+
 /*
 Example file:
 2-4,6-8
@@ -87,8 +258,9 @@ Example file:
 5-7,7-9
 */
 func main() {
-	// read a file from os.Argv[1]. using os.ReadFile
-	data, e := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// read a file from flag.Arg(0), or stdin when it is "-" or missing
+	data, e := ReadInput(flag.Arg(0))
 	Fatal(e)
 	lines := strings.Split(string(data), "\n")
 	// remove the last line if it's empty
@@ -96,12 +268,52 @@ func main() {
 		lines = lines[:len(lines)-1]
 	}
 
+	policies := map[string]ReversedPolicy{"reject": RejectReversed, "normalize": NormalizeReversed}
+	policy, ok := policies[*reversed]
+	if !ok {
+		Fatal(fmt.Errorf("unknown -reversed policy %q, want reject or normalize", *reversed))
+	}
+
 	// call PairsParser on each line
-	pairs := make([]Pairs, len(lines))
-	for i, line := range lines {
-		pairs[i] = PairsParser(line)
+	pairs, rejected := ParseLines(lines, policy)
+	if len(rejected) > 0 {
+		fmt.Fprintf(os.Stderr, "rejected %d of %d lines\n", len(rejected), len(lines))
+		for _, r := range rejected {
+			fmt.Fprintln(os.Stderr, r)
+		}
+		if *strict {
+			os.Exit(1)
+		}
 	}
 
 	fmt.Println(Part1(pairs))
 	fmt.Println(Part2(pairs))
+	if *atLeast > 0 {
+		fmt.Printf("Covered by at least %d: %d\n", *atLeast, CoveredAtLeast(pairs, *atLeast))
+	}
+
+	if *coverQuery == "" && !*concurrent && !*overlapping {
+		return
+	}
+	index := IndexPairs(pairs)
+	if *coverQuery != "" {
+		section, err := strconv.Atoi(*coverQuery)
+		Fatal(err)
+		found := index.Covering(section)
+		fmt.Printf("Section %d: %d assignments\n", section, len(found))
+		for _, a := range found {
+			fmt.Println(a)
+		}
+	}
+	if *concurrent {
+		most, where := index.MaxConcurrent()
+		fmt.Printf("Max concurrent: %d at sections %v\n", most, where)
+	}
+	if *overlapping {
+		found := index.Overlaps()
+		fmt.Println("Overlapping pairs:", len(found))
+		for _, pair := range found {
+			fmt.Printf("%s & %s\n", pair[0], pair[1])
+		}
+	}
 }
@@ -1,40 +1,227 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 )
 
+var (
+	draw   = flag.Bool("draw", false, "also print the final drawing of both parts")
+	atStep = flag.Int("step", -1, "print the drawing of both cranes after this many instructions")
+	replay = flag.Bool("replay", false, "print the drawing of both cranes after every instruction")
+	undo   = flag.Int("undo", 0, "after -step, -replay or all instructions, take back this many instructions one at a time and print the drawing after each")
+
+	craneNames = flag.String("crane", "", "comma separated cranes to use instead of the two parts: 9000, 9001, batch:K or zigzag:K")
+
+	finalTops    = flag.String("final-top", "", "instead of the answers, rebuild the starting stacks from this answer, crates other than the tops become ?")
+	finalDrawing = flag.String("final-drawing", "", "instead of the answers, rebuild the starting stacks from the final drawing in this file")
+)
+
 func Fatal(err error) {
 	if err != nil {
 		panic(err)
 	}
 }
 
-func PrettyPrintState(state []string) {
-	for _, line := range state {
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+/*
+A stack of crates, bottom crate first. Label is the stack's name from the
+header line of the drawing and crates keep their label without brackets,
+so [AB] is the crate "AB".
+*/
+type Stack struct {
+	Label  string
+	Crates []string
+}
+
+func (s Stack) Top() string {
+	if len(s.Crates) == 0 {
+		return ""
+	}
+	return s.Crates[len(s.Crates)-1]
+}
+
+// a deep copy, so moving crates around in it leaves the original alone
+func CopyState(state []Stack) []Stack {
+	copied := make([]Stack, len(state))
+	for i, s := range state {
+		copied[i] = Stack{s.Label, append([]string{}, s.Crates...)}
+	}
+	return copied
+}
+
+func PrettyPrintState(state []Stack) {
+	for _, line := range RenderDrawing(state) {
 		fmt.Println(line)
 	}
 }
 
+// a piece of a drawing line and the columns it covers, end excluded
+type span struct {
+	text       string
+	start, end int
+}
+
+func (s span) center() int {
+	return (s.start + s.end - 1) / 2
+}
+
+// the stack labels of the header line, split on spaces
+func headerSpans(line string) []span {
+	spans := []span{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(line) && line[j] != ' ' {
+			j++
+		}
+		spans = append(spans, span{line[i:j], i, j})
+		i = j
+	}
+	return spans
+}
+
+// the [crate] tokens of a drawing line
+func crateSpans(line string) ([]span, error) {
+	spans := []span{}
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+		case '[':
+			j := strings.IndexByte(line[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unclosed crate at column %d", i+1)
+			}
+			if j == 1 {
+				return nil, fmt.Errorf("crate without a label at column %d", i+1)
+			}
+			spans = append(spans, span{line[i+1 : i+j], i, i + j + 1})
+			i += j
+		default:
+			return nil, fmt.Errorf("unexpected %q at column %d", line[i], i+1)
+		}
+	}
+	return spans, nil
+}
+
+/*
+Parse the drawing above the empty line. The last line holds the stack
+labels, each crate belongs to the label that overlaps it, or the closest
+one when none does. Labels and crates can be any number of characters wide.
+*/
+func ParseDrawing(lines []string) ([]Stack, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("no drawing")
+	}
+	header := headerSpans(lines[len(lines)-1])
+	if len(header) == 0 {
+		return nil, errors.New("no stack labels in the last line of the drawing")
+	}
+	state := make([]Stack, len(header))
+	for i, h := range header {
+		state[i].Label = h.text
+	}
+
+	// read from the bottom up, so crates go on their stacks in order
+	for row := len(lines) - 2; row >= 0; row-- {
+		crates, err := crateSpans(lines[row])
+		if err != nil {
+			return nil, fmt.Errorf("drawing line %d: %w", row+1, err)
+		}
+		used := map[int]bool{}
+		for _, crate := range crates {
+			column := closestLabel(header, crate)
+			if used[column] {
+				return nil, fmt.Errorf("drawing line %d: two crates over stack %s", row+1, header[column].text)
+			}
+			used[column] = true
+			if len(state[column].Crates) != len(lines)-2-row {
+				return nil, fmt.Errorf("drawing line %d: crate [%s] floats over stack %s", row+1, crate.text, header[column].text)
+			}
+			state[column].Crates = append(state[column].Crates, crate.text)
+		}
+	}
+	return state, nil
+}
+
+func closestLabel(header []span, crate span) int {
+	best, bestDistance := 0, -1
+	for i, h := range header {
+		if h.start < crate.end && crate.start < h.end {
+			return i
+		}
+		distance := h.center() - crate.center()
+		if distance < 0 {
+			distance = -distance
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// pad text to width, with the extra space split around it
+func centered(text string, width int) string {
+	left := (width - len(text)) / 2
+	return strings.Repeat(" ", left) + text + strings.Repeat(" ", width-len(text)-left)
+}
+
 /*
-Transpose a multiline input, with all lines having the same length.
-Only check the length of the first line.
+Draw the stacks the way the puzzle input does, every stack a column as
+wide as the widest crate or label, columns separated by one space and
+lines padded to full width.
 */
-func Transpose(input []string) []string {
-	// Create a slice of strings to hold the transposed lines.
-	transposed := make([]string, len(input[0]))
-	// Loop over the lines.
-	for _, line := range input {
-		// Loop over the characters in the line.
-		for i, char := range line {
-			// Add the character to the transposed line.
-			transposed[i] += string(char)
+func RenderDrawing(state []Stack) []string {
+	width, height := 0, 0
+	for _, s := range state {
+		if len(s.Label) > width {
+			width = len(s.Label)
+		}
+		for _, crate := range s.Crates {
+			if len(crate)+2 > width {
+				width = len(crate) + 2
+			}
+		}
+		if len(s.Crates) > height {
+			height = len(s.Crates)
 		}
 	}
-	return transposed
+
+	lines := []string{}
+	for level := height - 1; level >= 0; level-- {
+		cells := []string{}
+		for _, s := range state {
+			cell := ""
+			if level < len(s.Crates) {
+				cell = "[" + s.Crates[level] + "]"
+			}
+			cells = append(cells, centered(cell, width))
+		}
+		lines = append(lines, strings.Join(cells, " "))
+	}
+	labels := []string{}
+	for _, s := range state {
+		labels = append(labels, centered(s.Label, width))
+	}
+	return append(lines, strings.Join(labels, " "))
 }
 
 /*
@@ -60,152 +247,477 @@ func SplitInput(input string) ([]string, []string) {
 	return firstPart, secondPart
 }
 
-/*
-Remove lines which do not contain a number anywhere.
-Reverse each line with a for loop.
-Remove the first character.
-Remove trailing spaces.
-*/
-func CleanFirstPart(input []string) []string {
-	// Create a slice of strings to hold the cleaned lines.
-	cleaned := make([]string, 0)
-	// Loop over the lines.
-	for _, line := range input {
-		// Check if the line contains a number.
-		if strings.ContainsAny(line, "0123456789") {
-			// Create a slice of runes to hold the reversed line.
-			reversed := make([]rune, len(line))
-			// Loop over the characters in the line.
-			for i, char := range line {
-				// Add the character to the reversed line.
-				reversed[len(line)-i-1] = char
-			}
-			// Convert the reversed line to a string.
-			reversedString := string(reversed)
-			// Remove the first character.
-			reversedString = reversedString[1:]
-			// Remove trailing spaces.
-			reversedString = strings.TrimRight(reversedString, " ")
-			// Add the reversed line to the cleaned lines.
-			cleaned = append(cleaned, reversedString)
-		}
-	}
-	return cleaned
-}
-
 /*
 Take instructions from the second part of the input file.
 The instructions are of the form:
 move X from Y to Z
 move 3 from 1 to 3
 The first number means how many, from means from which row and to means to which row.
-multiContainer is a boolean which indicates if the instructions are for the multi container function
-If multiContainer is true call MoveMultipleItemsFromTo, else call MoveSingleItemsFromTo. State is the last argument.
+The crane decides the order in which the crates land.
+firstLine is the line number of the first instruction in the input file.
+Stops at the first instruction that can not be done.
 Does not modify its input state.
 */
-func MoveItems(instructions []string, state []string, multiContainer bool) []string {
-	// Create a slice of strings to hold the new state.
-	newState := make([]string, len(state))
-	// Copy the state to the new state.
-	copy(newState, state)
-	// Loop over the instructions.
-	for _, instruction := range instructions {
-		// Split the instruction into words.
-		words := strings.Split(instruction, " ")
-		// Get the number of items to move.
-		number, err := strconv.Atoi(words[1])
-		Fatal(err)
-		// Get the row to move from.
-		from, err := strconv.Atoi(words[3])
-		Fatal(err)
-		// Get the row to move to.
-		to, err := strconv.Atoi(words[5])
-		Fatal(err)
-		// Move the items.
-		if multiContainer {
-			newState = MoveMultipleItemsFromTo(number, from, to, newState)
-		} else {
-			newState = MoveSingleItemsFromTo(number, from, to, newState)
+func MoveItems(instructions []string, state []Stack, crane Crane, firstLine int) ([]Stack, error) {
+	sim, err := NewSimulator(state, instructions, crane, firstLine)
+	if err != nil {
+		return nil, err
+	}
+	if err := sim.JumpTo(sim.Steps()); err != nil {
+		return nil, err
+	}
+	return sim.State(), nil
+}
+
+// one "move X from Y to Z" instruction, From and To are 1-based
+type Move struct {
+	HowMany, From, To int
+}
+
+func ParseMove(instruction string) (Move, error) {
+	var m Move
+	// Split the instruction into words.
+	words := strings.Split(instruction, " ")
+	if len(words) != 6 || words[0] != "move" || words[2] != "from" || words[4] != "to" {
+		return m, fmt.Errorf("bad instruction %q", instruction)
+	}
+	// Get the number of items to move, the row to move from and to.
+	for i, n := range []*int{&m.HowMany, &m.From, &m.To} {
+		number, err := strconv.Atoi(words[1+2*i])
+		if err != nil {
+			return m, fmt.Errorf("bad number in %q: %w", instruction, err)
 		}
+		*n = number
 	}
-	return newState
+	return m, nil
 }
 
+func (m Move) String() string {
+	return fmt.Sprintf("move %d from %d to %d", m.HowMany, m.From, m.To)
+}
+
+// why the move can not be done on state, or nil when it can
+func (m Move) Check(state []Stack) error {
+	switch {
+	case m.HowMany < 0:
+		return fmt.Errorf("can not move %d crates", m.HowMany)
+	case m.From < 1 || m.From > len(state):
+		return fmt.Errorf("no stack %d, there are %d", m.From, len(state))
+	case m.To < 1 || m.To > len(state):
+		return fmt.Errorf("no stack %d, there are %d", m.To, len(state))
+	case len(state[m.From-1].Crates) < m.HowMany:
+		return fmt.Errorf("stack %d has only %d crates", m.From, len(state[m.From-1].Crates))
+	}
+	return nil
+}
+
+/*
+	Cranes
+*/
+
 /*
-Move single items from one row to another.
-HowMany means one by one, a repeated action.
-From is from which row. To is to which row.
-The from and to indexes are 1-based.
+A crane takes the top crates of a stack and puts them on another one.
+Arrange gets the taken crates bottom first and returns them in the order
+they end up in on the other stack, bottom first. It must only reorder
+them.
 */
-func MoveSingleItemsFromTo(howMany, from, to int, state []string) []string {
-	//fmt.Println("=== Move", howMany, "from", from, "to", to)
-	// Loop over the number of items to move.
-	for i := 0; i < howMany; i++ {
-		// Get the last item from the from row.
-		item := state[from-1][len(state[from-1])-1:]
-		// Remove the last item from the from row.
-		state[from-1] = state[from-1][:len(state[from-1])-1]
-		// Add the item to the to row.
-		state[to-1] += item
-		// prettyprint the state
-		//PrettyPrintState(state)
+type Crane interface {
+	Name() string
+	Arrange(crates []string) []string
+}
+
+// CrateMover 9000, one crate at a time
+type SingleCrane struct{}
+
+func (SingleCrane) Name() string { return "9000" }
+
+func (SingleCrane) Arrange(crates []string) []string {
+	arranged := make([]string, len(crates))
+	for i, crate := range crates {
+		arranged[len(crates)-1-i] = crate
 	}
-	return state
+	return arranged
+}
+
+// CrateMover 9001, all crates at once
+type MultiCrane struct{}
+
+func (MultiCrane) Name() string { return "9001" }
+
+func (MultiCrane) Arrange(crates []string) []string {
+	return append([]string{}, crates...)
+}
+
+/*
+a crane that lifts at most K crates at once, batches keep their order.
+with Zigzag every other batch is turned around on the way.
+*/
+type BatchCrane struct {
+	K      int
+	Zigzag bool
+}
+
+func (c BatchCrane) Name() string {
+	if c.Zigzag {
+		return fmt.Sprintf("zigzag:%d", c.K)
+	}
+	return fmt.Sprintf("batch:%d", c.K)
+}
+
+func (c BatchCrane) Arrange(crates []string) []string {
+	arranged := []string{}
+	for batch, end := 0, len(crates); end > 0; batch, end = batch+1, end-c.K {
+		start := end - c.K
+		if start < 0 {
+			start = 0
+		}
+		lifted := append([]string{}, crates[start:end]...)
+		if c.Zigzag && batch%2 == 1 {
+			lifted = SingleCrane{}.Arrange(lifted)
+		}
+		arranged = append(arranged, lifted...)
+	}
+	return arranged
+}
+
+// the cranes by name, the ones taking a number are written name:K
+var CraneModels = map[string]func(k int) Crane{
+	"9000":   func(int) Crane { return SingleCrane{} },
+	"9001":   func(int) Crane { return MultiCrane{} },
+	"batch":  func(k int) Crane { return BatchCrane{k, false} },
+	"zigzag": func(k int) Crane { return BatchCrane{k, true} },
+}
+
+func CraneByName(name string) (Crane, error) {
+	model, arg, hasArg := strings.Cut(name, ":")
+	newCrane, ok := CraneModels[model]
+	if !ok {
+		return nil, fmt.Errorf("unknown crane %q", name)
+	}
+	k := 0
+	if hasArg {
+		var err error
+		if k, err = strconv.Atoi(arg); err != nil || k < 1 {
+			return nil, fmt.Errorf("crane %q needs a positive number after the colon", name)
+		}
+	} else if model == "batch" || model == "zigzag" {
+		return nil, fmt.Errorf("crane %q needs the batch size, %s:K", name, model)
+	}
+	return newCrane(k), nil
 }
 
 /*
-just from signature
+Move crates with a crane. The move has to pass Check first.
 */
-func MoveMultipleItemsFromTo(howMany, from, to int, state []string) []string {
-	//fmt.Println("=== Move", howMany, "from", from, "to", to)
-	// Get the items to move.
-	items := state[from-1][len(state[from-1])-howMany:]
-	// Remove the items from the from row.
-	state[from-1] = state[from-1][:len(state[from-1])-howMany]
+func MoveWith(crane Crane, m Move, state []Stack) []Stack {
+	src, dst := &state[m.From-1], &state[m.To-1]
+	// Get the items to move and remove them from the from row.
+	taken := append([]string{}, src.Crates[len(src.Crates)-m.HowMany:]...)
+	src.Crates = src.Crates[:len(src.Crates)-m.HowMany]
 	// Add the items to the to row.
-	state[to-1] += items
-	// prettyprint the state
-	//PrettyPrintState(state)
+	dst.Crates = append(dst.Crates, crane.Arrange(taken)...)
 	return state
 }
 
 /*
-Return a string concatenated from the last element of each row.
+Put the crates of a move back. Arranging their positions shows where each
+crate went, so they are put back in the order they were taken in.
 */
-func GetResult(state []string) string {
+func UnmoveWith(crane Crane, m Move, state []Stack) []Stack {
+	src, dst := &state[m.From-1], &state[m.To-1]
+	positions := make([]string, m.HowMany)
+	for i := range positions {
+		positions[i] = strconv.Itoa(i)
+	}
+	arranged := dst.Crates[len(dst.Crates)-m.HowMany:]
+	taken := make([]string, m.HowMany)
+	for i, position := range crane.Arrange(positions) {
+		p, _ := strconv.Atoi(position)
+		taken[p] = arranged[i]
+	}
+	dst.Crates = dst.Crates[:len(dst.Crates)-m.HowMany]
+	src.Crates = append(src.Crates, taken...)
+	return state
+}
+
+/*
+Return a string concatenated from the top crate of each stack.
+Empty stacks add nothing.
+*/
+func GetResult(state []Stack) string {
 	// Create a slice of strings to hold the result.
 	result := make([]string, 0)
-	// Loop over the rows.
-	for _, row := range state {
-		// Get the last character from the row.
-		char := row[len(row)-1:]
-		// Add the character to the result.
-		result = append(result, char)
+	// Loop over the stacks.
+	for _, stack := range state {
+		result = append(result, stack.Top())
 	}
 	// Join the result into a string.
 	return strings.Join(result, "")
 }
 
 /*
-read input with with os.ReadFile from os.Argv[1]
+	Simulator
+*/
+
+// an instruction that can not be parsed or done, Line is in the input file
+type InstructionError struct {
+	Line        int
+	Instruction string
+	Err         error
+}
+
+func (e InstructionError) Error() string {
+	return fmt.Sprintf("line %d: %q: %v", e.Line, e.Instruction, e.Err)
+}
+
+/*
+Run instructions one step at a time. The instruction list is fixed, so
+going back is only undoing steps, with Undo or JumpTo to an earlier step,
+and going forward again is Next. -undo walks back through Undo. Undoing only needs the crane to put the
+crates back, no copies of the stacks are kept.
+*/
+type Simulator struct {
+	instructions []string
+	moves        []Move
+	firstLine    int
+	crane        Crane
+	state        []Stack
+	step         int // moves applied so far
+}
+
+func NewSimulator(state []Stack, instructions []string, crane Crane, firstLine int) (*Simulator, error) {
+	sim := &Simulator{instructions: instructions, firstLine: firstLine, crane: crane, state: CopyState(state)}
+	for i, instruction := range instructions {
+		move, err := ParseMove(instruction)
+		if err != nil {
+			return nil, InstructionError{firstLine + i, instruction, err}
+		}
+		sim.moves = append(sim.moves, move)
+	}
+	return sim, nil
+}
+
+// the stacks after the current step, not to be modified
+func (s *Simulator) State() []Stack {
+	return s.state
+}
+
+func (s *Simulator) Step() int {
+	return s.step
+}
+
+func (s *Simulator) Steps() int {
+	return len(s.moves)
+}
+
+// apply the next instruction
+func (s *Simulator) Next() error {
+	if s.step == len(s.moves) {
+		return errors.New("no instructions left")
+	}
+	move := s.moves[s.step]
+	if err := move.Check(s.state); err != nil {
+		return InstructionError{s.firstLine + s.step, s.instructions[s.step], err}
+	}
+	s.state = MoveWith(s.crane, move, s.state)
+	s.step++
+	return nil
+}
+
+// take back the last instruction
+func (s *Simulator) Undo() error {
+	if s.step == 0 {
+		return errors.New("nothing to undo")
+	}
+	s.step--
+	s.state = UnmoveWith(s.crane, s.moves[s.step], s.state)
+	return nil
+}
+
+// go forwards or backwards until n instructions have been applied
+func (s *Simulator) JumpTo(n int) error {
+	if n < 0 || n > len(s.moves) {
+		return fmt.Errorf("step %d is not between 0 and %d", n, len(s.moves))
+	}
+	for s.step > n {
+		if err := s.Undo(); err != nil {
+			return err
+		}
+	}
+	for s.step < n {
+		if err := s.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the drawing after the current step, with the step as a heading
+func (s *Simulator) Print() {
+	heading := "start"
+	if s.step > 0 {
+		heading = s.moves[s.step-1].String()
+	}
+	fmt.Printf("== crane %s, step %d/%d: %s\n", s.crane.Name(), s.step, len(s.moves), heading)
+	PrettyPrintState(s.state)
+}
+
+/*
+	Inverse solver
+*/
+
+/*
+Run the instructions backwards from a final state to the state they
+started from. Every move is undone with the crane that made it, so the
+result moved forward with the same crane gives final again.
+*/
+func Unsolve(final []Stack, instructions []string, crane Crane, firstLine int) ([]Stack, error) {
+	state := CopyState(final)
+	for i := len(instructions) - 1; i >= 0; i-- {
+		move, err := ParseMove(instructions[i])
+		if err == nil {
+			// undoing takes the crates back off the to stack
+			err = Move{move.HowMany, move.To, move.From}.Check(state)
+		}
+		if err != nil {
+			return nil, InstructionError{firstLine + i, instructions[i], err}
+		}
+		state = UnmoveWith(crane, move, state)
+	}
+	return state, nil
+}
+
+/*
+A final state that only knows the top crates. The heights of the stacks
+come from moving the crates of shape, the answer gives the top crate of
+every stack that is not empty and all other crates are "?". Crates wider
+than one character are given separated by commas.
+*/
+func FinalFromTops(shape []Stack, instructions []string, crane Crane, firstLine int, answer string) ([]Stack, error) {
+	final, err := MoveItems(instructions, shape, crane, firstLine)
+	if err != nil {
+		return nil, err
+	}
+	tops := strings.Split(answer, "")
+	if strings.Contains(answer, ",") {
+		tops = strings.Split(answer, ",")
+	}
+
+	next := 0
+	for i := range final {
+		if len(final[i].Crates) == 0 {
+			continue
+		}
+		if next == len(tops) {
+			return nil, fmt.Errorf("answer %q is too short for %d stacks with crates", answer, next+1)
+		}
+		for j := range final[i].Crates {
+			final[i].Crates[j] = "?"
+		}
+		final[i].Crates[len(final[i].Crates)-1] = tops[next]
+		next++
+	}
+	if next < len(tops) {
+		return nil, fmt.Errorf("answer %q has %d tops, but only %d stacks have crates", answer, len(tops), next)
+	}
+	return final, nil
+}
+
+/*
+read input with ReadInput from flag.Arg(0)
 SplitInput() on input.
-Transpose() the first part.
-CleanFirstPart() the transposed lines.
-First call MoveItems(cleaned, single) and print out the result as Part1.
-Then call MoveItems(cleaned, multi) and print out the result as Part2.
-Use the same instance of cleaned data on both calls.
+ParseDrawing() the first part.
+First call MoveItems(stacks, 9000) and print out the result as Part1.
+Then call MoveItems(stacks, 9001) and print out the result as Part2.
+Use the same instance of parsed stacks on both calls.
 */
 func main() {
+	flag.Parse()
 	// Read the input file.
-	input, err := os.ReadFile(os.Args[1])
+	input, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// Split the input into two parts.
 	firstPart, secondPart := SplitInput(string(input))
-	// Transpose the first part.
-	firstPartTransposed := Transpose(firstPart)
-	// Clean the first part.
-	firstPartCleaned := CleanFirstPart(firstPartTransposed)
+	// the drawing, an empty line and then the instructions
+	firstLine := len(firstPart) + 2
+	// Parse the drawing.
+	stacks, err := ParseDrawing(firstPart)
+	Fatal(err)
+
+	cranes := []Crane{SingleCrane{}, MultiCrane{}}
+	if *craneNames != "" {
+		cranes = nil
+		for _, name := range strings.Split(*craneNames, ",") {
+			crane, err := CraneByName(name)
+			Fatal(err)
+			cranes = append(cranes, crane)
+		}
+	}
+
+	if *finalTops != "" || *finalDrawing != "" {
+		for _, crane := range cranes {
+			var final []Stack
+			if *finalDrawing != "" {
+				data, err := os.ReadFile(*finalDrawing)
+				Fatal(err)
+				final, err = ParseDrawing(strings.Split(strings.TrimRight(string(data), "\n"), "\n"))
+				Fatal(err)
+			} else {
+				final, err = FinalFromTops(stacks, secondPart, crane, firstLine, *finalTops)
+				Fatal(err)
+			}
+			start, err := Unsolve(final, secondPart, crane, firstLine)
+			Fatal(err)
+			fmt.Printf("== crane %s, starting stacks\n", crane.Name())
+			PrettyPrintState(start)
+
+			// moving forward again has to give the final state back
+			check, err := MoveItems(secondPart, start, crane, firstLine)
+			Fatal(err)
+			if strings.Join(RenderDrawing(check), "\n") != strings.Join(RenderDrawing(final), "\n") {
+				Fatal(fmt.Errorf("crane %s does not get back to the final state", crane.Name()))
+			}
+			fmt.Println("Top:", GetResult(start))
+		}
+		return
+	}
+
 	// Move the items and print out the result.
-	fmt.Println("Part1:", GetResult(MoveItems(secondPart, firstPartCleaned, false)))
-	fmt.Println("Part2:", GetResult(MoveItems(secondPart, firstPartCleaned, true)))
+	for i, crane := range cranes {
+		final, err := MoveItems(secondPart, stacks, crane, firstLine)
+		Fatal(err)
+		if *craneNames == "" {
+			fmt.Printf("Part%d: %s\n", i+1, GetResult(final))
+		} else {
+			fmt.Printf("Crane %s: %s\n", crane.Name(), GetResult(final))
+		}
+		if *draw {
+			PrettyPrintState(final)
+		}
+	}
+
+	if *atStep < 0 && !*replay && *undo == 0 {
+		return
+	}
+	for _, crane := range cranes {
+		sim, err := NewSimulator(stacks, secondPart, crane, firstLine)
+		Fatal(err)
+		switch {
+		case *replay:
+			sim.Print()
+			for sim.Step() < sim.Steps() {
+				Fatal(sim.Next())
+				sim.Print()
+			}
+		case *atStep >= 0:
+			Fatal(sim.JumpTo(*atStep))
+			sim.Print()
+		default:
+			Fatal(sim.JumpTo(sim.Steps()))
+		}
+		for i := 0; i < *undo; i++ {
+			Fatal(sim.Undo())
+			sim.Print()
+		}
+	}
 }
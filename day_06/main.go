@@ -2,8 +2,19 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	all     = flag.Bool("all", false, "stream the input and print every marker instead of the first ones")
+	windows = flag.String("windows", "4,14", "comma separated window sizes for -all and -report")
+	report  = flag.Bool("report", false, "print a table per line with the first marker of every window size, the longest run of different characters and how often it breaks")
+	breaks  = flag.Bool("breaks", false, "list the break positions in the -report table")
 )
 
 // from signature
@@ -13,64 +24,305 @@ func Panic(err error) {
 	}
 }
 
-// from signature
-func ReadFileToLines(filename string) []string {
-	file, err := os.Open(filename)
-	Panic(err)
-	defer file.Close()
+/*
+open the input file. the path "-" or no path at all reads stdin.
+*/
+func OpenInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
+/*
+call handle with each line of the reader as soon as it has been read,
+the lines are not collected. a single line may be up to 1GB long.
+*/
+func ForEachLine(r io.Reader, handle func(line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
 	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+		handle(scanner.Text())
 	}
 	Panic(scanner.Err())
+}
+
+/*
+A sliding window over a stream of bytes that knows in O(1) per byte if
+the last size bytes are all different. counts holds how often each byte
+value is in the window and distinct how many values are in it at all.
+*/
+type Detector struct {
+	size     int
+	window   []byte
+	counts   [256]int
+	distinct int
+	seen     int64
+}
 
-	return lines
+func NewDetector(size int) *Detector {
+	if size < 1 {
+		panic(fmt.Sprintf("window size %d, must be at least 1", size))
+	}
+	return &Detector{size: size, window: make([]byte, size)}
+}
+
+// forget everything, for the start of a new line
+func (d *Detector) Reset() {
+	d.counts = [256]int{}
+	d.distinct = 0
+	d.seen = 0
 }
 
 /*
-Iterate over characters in a line, with a sliding window of length <seqLen>.
-Use a single <seqLen> sized buffer to store the current sequence. Use modulus to assign into it.
-Collect all 4 characters in the window in a map.
-For each window, create map to check if all the characters inside it are different from each other.
-If the map has a length of <seqLen>, then all characters are unique.
-If they are, return the index of the last character in the window.
+slide the window over the next byte. returns true when the window is full
+and all of its bytes are different, so a marker ends at this byte.
 */
-func FindFirstUniqueChar(line string, seqLen int) int {
-	// Initialize the buffer
-	buffer := make([]byte, seqLen)
-	for i := 0; i < seqLen; i++ {
-		buffer[i] = line[i]
+func (d *Detector) Add(b byte) bool {
+	slot := d.seen % int64(d.size)
+	if d.seen >= int64(d.size) {
+		old := d.window[slot]
+		d.counts[old]--
+		if d.counts[old] == 0 {
+			d.distinct--
+		}
 	}
+	d.window[slot] = b
+	d.counts[b]++
+	if d.counts[b] == 1 {
+		d.distinct++
+	}
+	d.seen++
+	return d.distinct == d.size
+}
+
+/*
+A marker is complete after Offset bytes of its line, which is the puzzle
+answer when it is the first one. Lines count from 1.
+*/
+type Marker struct {
+	Line   int
+	Window int
+	Offset int64
+}
 
-	// Iterate over the line
-	for i := seqLen; i < len(line); i++ {
-		// Check if all characters in the buffer are unique
-		charMap := make(map[byte]bool)
-		for _, char := range buffer {
-			charMap[char] = true
+/*
+Read the stream in blocks and call handle with every byte and endLine at
+the end of every line, also the last one when it has no newline. Nothing
+but the block is kept in memory, so lines can be of any length. A \r is
+ignored.
+*/
+func ForEachByte(r io.Reader, handle func(b byte), endLine func()) error {
+	buffer := make([]byte, 64*1024)
+	open := false
+	for {
+		n, err := r.Read(buffer)
+		for _, b := range buffer[:n] {
+			switch b {
+			case '\n':
+				endLine()
+				open = false
+			case '\r':
+			default:
+				handle(b)
+				open = true
+			}
 		}
-		if len(charMap) == seqLen {
-			return i
+		if err == io.EOF {
+			if open {
+				endLine()
+			}
+			return nil
 		}
+		if err != nil {
+			return err
+		}
+	}
+}
 
-		// Add the next character to the buffer
-		buffer[i%seqLen] = line[i]
+/*
+Emit every marker of every window size, in the order they complete.
+Only the windows are kept in memory. A newline starts over.
+*/
+func Markers(r io.Reader, sizes []int, emit func(m Marker)) error {
+	detectors := make([]*Detector, len(sizes))
+	for i, size := range sizes {
+		detectors[i] = NewDetector(size)
+	}
+
+	line := 1
+	var offset int64
+	return ForEachByte(r, func(b byte) {
+		offset++
+		for i, d := range detectors {
+			if d.Add(b) {
+				emit(Marker{line, sizes[i], offset})
+			}
+		}
+	}, func() {
+		line++
+		offset = 0
+		for _, d := range detectors {
+			d.Reset()
+		}
+	})
+}
+
+/*
+	Signal analysis
+*/
+
+/*
+What one pass over a line finds. First holds the first marker of each
+window size in the order they were asked for, -1 when there is none.
+The longest run of different characters ends at LongestEnd. A break is
+a position whose character is already in the run before it, so the run
+starts over after the earlier copy. Positions count from 1.
+*/
+type LineAnalysis struct {
+	Line       int
+	Length     int64
+	First      []int64
+	Longest    int64
+	LongestEnd int64
+	BreakCount int64
+	Breaks     []int64
+}
+
+/*
+Analyze every line in a single pass. Instead of one window per size it
+keeps the run of different characters ending at the current position,
+with the last position of every byte value. A window of size k ends in
+a marker exactly when that run is at least k long, so the first markers
+of all sizes fall out of the run length. Break positions are only kept
+when keepBreaks is set, their count always.
+*/
+func Analyze(r io.Reader, windows []int, keepBreaks bool, emit func(a LineAnalysis)) error {
+	var last [256]int64
+	var runStart int64
+	var a LineAnalysis
+	reset := func(line int) {
+		last = [256]int64{}
+		runStart = 1
+		a = LineAnalysis{Line: line, First: make([]int64, len(windows))}
+		for i := range a.First {
+			a.First[i] = -1
+		}
+	}
+	reset(1)
+
+	return ForEachByte(r, func(b byte) {
+		a.Length++
+		at := a.Length
+		if last[b] >= runStart {
+			runStart = last[b] + 1
+			a.BreakCount++
+			if keepBreaks {
+				a.Breaks = append(a.Breaks, at)
+			}
+		}
+		last[b] = at
+
+		run := at - runStart + 1
+		if run > a.Longest {
+			a.Longest, a.LongestEnd = run, at
+		}
+		for i, size := range windows {
+			if a.First[i] < 0 && run >= int64(size) {
+				a.First[i] = at
+			}
+		}
+	}, func() {
+		emit(a)
+		reset(a.Line + 1)
+	})
+}
+
+// one row per line, - for windows without a marker
+func PrintAnalysis(w io.Writer, windows []int, rows []LineAnalysis) {
+	fmt.Fprintf(w, "%-6s %10s", "line", "length")
+	for _, size := range windows {
+		fmt.Fprintf(w, " %10s", fmt.Sprintf("window %d", size))
+	}
+	fmt.Fprintf(w, " %8s %10s %10s\n", "longest", "ends at", "breaks")
+	for _, a := range rows {
+		fmt.Fprintf(w, "%-6d %10d", a.Line, a.Length)
+		for _, first := range a.First {
+			if first < 0 {
+				fmt.Fprintf(w, " %10s", "-")
+			} else {
+				fmt.Fprintf(w, " %10d", first)
+			}
+		}
+		fmt.Fprintf(w, " %8d %10d %10d\n", a.Longest, a.LongestEnd, a.BreakCount)
+		if len(a.Breaks) > 0 {
+			breaks := []string{}
+			for _, at := range a.Breaks {
+				breaks = append(breaks, strconv.FormatInt(at, 10))
+			}
+			fmt.Fprintf(w, "       breaks at %s\n", strings.Join(breaks, " "))
+		}
+	}
+}
+
+/*
+The first marker of seqLen different characters in a line, counted in
+characters up to and including its last one, or -1 when there is none.
+*/
+func FindFirstUniqueChar(line string, seqLen int) int {
+	d := NewDetector(seqLen)
+	for i := 0; i < len(line); i++ {
+		if d.Add(line[i]) {
+			return i + 1
+		}
 	}
 	return -1
 }
 
 /*
-Read file using ReadFileToLines. Path is in os.Args[1].
+Read file line by line using ForEachLine. Path is in flag.Arg(0).
 For each line, call FindFirstUniqueChar and print the line with the result with prefix Part1.
 Use seqLen 4 for Part1.
 Then use seqLen 14 for Part2.
+With -all stream the input instead and print every marker of the -windows sizes.
+With -report print the analysis table of every line.
 */
 func main() {
-	lines := ReadFileToLines(os.Args[1])
-	for _, line := range lines {
+	flag.Parse()
+	input, err := OpenInput(flag.Arg(0))
+	Panic(err)
+	defer input.Close()
+
+	sizes := []int{}
+	for _, field := range strings.Split(*windows, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		Panic(err)
+		if size < 1 {
+			panic(fmt.Sprintf("window size %d, must be at least 1", size))
+		}
+		sizes = append(sizes, size)
+	}
+
+	if *report {
+		rows := []LineAnalysis{}
+		Panic(Analyze(input, sizes, *breaks, func(a LineAnalysis) {
+			rows = append(rows, a)
+		}))
+		PrintAnalysis(os.Stdout, sizes, rows)
+		return
+	}
+
+	if *all {
+		out := bufio.NewWriter(os.Stdout)
+		defer out.Flush()
+		Panic(Markers(input, sizes, func(m Marker) {
+			fmt.Fprintf(out, "line %d window %d offset %d\n", m.Line, m.Window, m.Offset)
+		}))
+		return
+	}
+
+	ForEachLine(input, func(line string) {
 		fmt.Printf("Part1: %s %d\n", line, FindFirstUniqueChar(line, 4))
 		fmt.Printf("Part2: %s %d\n", line, FindFirstUniqueChar(line, 14))
-	}
+	})
 }
@@ -2,11 +2,19 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func Fatal(err error) {
@@ -15,109 +23,479 @@ func Fatal(err error) {
 	}
 }
 
+/*
+A directory of the reconstructed filesystem. Files and sub directories
+are kept by name, so a directory that is listed twice does not get its
+entries twice. ShallowSize is the size of its own files, DeepSize also
+counts everything below it, both are set by FindTotalSizes.
+*/
 type Directory struct {
-	Fullpath      string
-	ChildrenNames []string
-	Files         []string
-	DeepSize      int
-	ShallowSize   int
-	Listed        bool
+	Name        string
+	Parent      *Directory
+	Dirs        map[string]*Directory
+	Files       map[string]*File
+	DeepSize    int
+	ShallowSize int
+	Listed      bool
 }
 
-func ChopDirOff(fullpath string) string {
-	if strings.Count(fullpath, "/") == 1 {
-		return "/"
+type File struct {
+	Name   string
+	Size   int
+	Parent *Directory
+}
+
+func NewDirectory(name string, parent *Directory) *Directory {
+	return &Directory{Name: name, Parent: parent, Dirs: map[string]*Directory{}, Files: map[string]*File{}}
+}
+
+// the sub directory with the name, created when it is not known yet
+func (d *Directory) Dir(name string) *Directory {
+	if _, ok := d.Dirs[name]; !ok {
+		d.Dirs[name] = NewDirectory(name, d)
 	}
-	return strings.Join(strings.Split(fullpath, "/")[:len(strings.Split(fullpath, "/"))-1], "/")
+	return d.Dirs[name]
 }
 
-func PushOnDir(fullpath string, dir string) string {
-	if fullpath == "/" {
-		return "/" + dir
+func (d *Directory) Root() *Directory {
+	for d.Parent != nil {
+		d = d.Parent
 	}
-	if fullpath == "/" && dir == "/" {
+	return d
+}
+
+// the absolute path, / for the root
+func (d *Directory) Fullpath() string {
+	if d.Parent == nil {
 		return "/"
 	}
-	if fullpath == "" && dir == "/" {
-		return "/"
+	return path.Join(d.Parent.Fullpath(), d.Name)
+}
+
+// call visit with the directory and every directory below it
+func (d *Directory) Walk(visit func(dir *Directory)) {
+	visit(d)
+	for _, name := range sortedKeys(d.Dirs) {
+		d.Dirs[name].Walk(visit)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+the directory at p, absolute when it starts with /, otherwise relative to
+d. "." and ".." work as usual, going above / is an error. A name that is
+not known yet is created as long as its directory has not been listed,
+a listed directory that lacks it is an error.
+*/
+func (d *Directory) Resolve(p string) (*Directory, error) {
+	dir := d
+	if strings.HasPrefix(p, "/") {
+		dir = d.Root()
+	}
+	for _, name := range strings.Split(p, "/") {
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if dir.Parent == nil {
+				return nil, errors.New("already at /, there is no ..")
+			}
+			dir = dir.Parent
+			continue
+		}
+		if _, ok := dir.Files[name]; ok {
+			return nil, fmt.Errorf("%s is a file", path.Join(dir.Fullpath(), name))
+		}
+		if _, ok := dir.Dirs[name]; !ok && dir.Listed {
+			return nil, fmt.Errorf("no directory %s in listed %s", name, dir.Fullpath())
+		}
+		dir = dir.Dir(name)
+	}
+	return dir, nil
+}
+
+// add a listed sub directory, a file of the same name is an error
+func (d *Directory) AddDir(name string) error {
+	if _, ok := d.Files[name]; ok {
+		return fmt.Errorf("%s is listed as a file before", path.Join(d.Fullpath(), name))
+	}
+	d.Dir(name)
+	return nil
+}
+
+/*
+add a listed file. listing a file again replaces its size, so listing a
+directory twice counts it only once.
+*/
+func (d *Directory) AddFile(name string, size int) error {
+	if _, ok := d.Dirs[name]; ok {
+		return fmt.Errorf("%s is listed as a directory before", path.Join(d.Fullpath(), name))
+	}
+	d.Files[name] = &File{name, size, d}
+	return nil
+}
+
+/*
+Shell replays a terminal transcript. Lines starting with "$ " are
+commands, the other lines are the output of the last command:
+
+	cd DIR       change to DIR, absolute or relative, / and .. included
+	ls [DIR]     list DIR or the current directory, the output follows
+	pwd          the output, when there is one, must be the current path
+	mkdir DIR..  make directories, their parent must exist
+*/
+type Shell struct {
+	Root *Directory
+	Cwd  *Directory
+
+	listing *Directory // the directory the output lines belong to
+	pwd     bool       // the output line is expected to be the current path
+}
+
+func NewShell() *Shell {
+	root := NewDirectory("", nil)
+	return &Shell{Root: root, Cwd: root}
+}
+
+type TranscriptError struct {
+	Line    int
+	Content string
+	Err     error
+}
+
+func (e *TranscriptError) Error() string {
+	return fmt.Sprintf("line %d: %q: %v", e.Line, e.Content, e.Err)
+}
+
+func (e *TranscriptError) Unwrap() error {
+	return e.Err
+}
+
+// run one line of the transcript
+func (s *Shell) Run(line string) error {
+	if command, ok := strings.CutPrefix(line, "$ "); ok {
+		s.listing, s.pwd = nil, false
+		return s.command(strings.Fields(command))
+	}
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if s.pwd {
+		s.pwd = false
+		if line != s.Cwd.Fullpath() {
+			return fmt.Errorf("pwd says %s, the current directory is %s", line, s.Cwd.Fullpath())
+		}
+		return nil
+	}
+	if s.listing == nil {
+		return errors.New("output without an ls")
+	}
+	parts := strings.Fields(line)
+	if len(parts) != 2 {
+		return errors.New("want dir NAME or SIZE NAME")
+	}
+	if parts[0] == "dir" {
+		return s.listing.AddDir(parts[1])
+	}
+	size, err := strconv.Atoi(parts[0])
+	if err != nil || size < 0 {
+		return fmt.Errorf("bad size %q", parts[0])
+	}
+	return s.listing.AddFile(parts[1], size)
+}
+
+func (s *Shell) command(args []string) error {
+	if len(args) == 0 {
+		return errors.New("empty command")
 	}
-	return fullpath + "/" + dir
+	switch args[0] {
+	case "cd":
+		if len(args) != 2 {
+			return errors.New("cd wants one directory")
+		}
+		dir, err := s.Cwd.Resolve(args[1])
+		if err != nil {
+			return fmt.Errorf("cd %s: %w", args[1], err)
+		}
+		s.Cwd = dir
+	case "ls":
+		if len(args) > 2 {
+			return errors.New("ls wants at most one directory")
+		}
+		dir := s.Cwd
+		if len(args) == 2 {
+			var err error
+			if dir, err = s.Cwd.Resolve(args[1]); err != nil {
+				return fmt.Errorf("ls %s: %w", args[1], err)
+			}
+		}
+		dir.Listed = true
+		s.listing = dir
+	case "pwd":
+		if len(args) != 1 {
+			return errors.New("pwd takes no arguments")
+		}
+		s.pwd = true
+	case "mkdir":
+		if len(args) < 2 {
+			return errors.New("mkdir wants a directory")
+		}
+		for _, p := range args[1:] {
+			if err := s.mkdir(p); err != nil {
+				return fmt.Errorf("mkdir %s: %w", p, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return nil
 }
 
-func HandleChangeDirectory(dirs *map[string]*Directory, currentDir *Directory, newDir string) *Directory {
-	if newDir == ".." {
-		newDir = ChopDirOff(currentDir.Fullpath)
-		currentDir = (*dirs)[newDir]
-		return currentDir
+func (s *Shell) mkdir(p string) error {
+	parentPath, name := path.Split(strings.TrimSuffix(p, "/"))
+	if name == "" || name == "." || name == ".." {
+		return errors.New("not a new directory")
 	}
-	newDir = PushOnDir(currentDir.Fullpath, newDir)
-	if _, ok := (*dirs)[newDir]; !ok {
-		(*dirs)[newDir] = &Directory{
-			Fullpath:      newDir,
-			ChildrenNames: []string{},
-			Files:         []string{},
-			ShallowSize:   0,
-			DeepSize:      0,
-			Listed:        false,
+	parent := s.Cwd
+	if parentPath != "" {
+		var err error
+		if parent, err = s.Cwd.Resolve(parentPath); err != nil {
+			return err
 		}
 	}
-	return (*dirs)[newDir]
+	if _, ok := parent.Dirs[name]; ok {
+		return errors.New("already exists")
+	}
+	return parent.AddDir(name)
 }
 
 /*
 Processes a list of simulated CLI directory traversal commands and file listings.
-Collects the information received into a map of Directory structs.
-Commands starting with '$' are either 'cd' or 'ls' commands. Eg. '$ cd foo' or '$ cd ..'
-'cd' command changes the current directory.
-'ls' command lists the files and directories in the current directory.
-Name the command parts[2] as targetDirName
-ls output is in the following format:
+Collects the information received into a tree of Directory structs, the root is returned.
+The first line that does not make sense stops it with a TranscriptError.
+A typical transcript looks like:
+$ cd /
+$ ls
 dir somedirectory
 12345 somefile
+$ cd somedirectory
 ...
 */
-func ProcessCommands(commands []string) map[string]*Directory {
-	dirs := make(map[string]*Directory)
-	var currentDir *Directory = &Directory{}
-	for _, command := range commands {
-		parts := strings.Split(command, " ")
-		if parts[0] == "$" {
-			if parts[1] == "cd" {
-				currentDir = HandleChangeDirectory(&dirs, currentDir, parts[2])
-				dirs[currentDir.Fullpath] = currentDir
-			} else if parts[1] == "ls" {
-				currentDir.Listed = true
-			} else {
-				log.Fatal("Unknown command: ", parts[1])
-			}
-		} else {
-			if parts[0] == "dir" {
-				currentDir.ChildrenNames = append(currentDir.ChildrenNames, parts[1])
-			} else {
-				currentDir.Files = append(currentDir.Files, parts[1])
-				size, err := strconv.Atoi(parts[0])
-				Fatal(err)
-				currentDir.ShallowSize += size
-			}
+func ProcessCommands(commands []string) (*Directory, error) {
+	shell := NewShell()
+	for i, command := range commands {
+		if err := shell.Run(command); err != nil {
+			return shell.Root, &TranscriptError{i + 1, command, err}
 		}
 	}
-	return dirs
+	return shell.Root, nil
+}
+
+func FindTotalSizes(dir *Directory) {
+	dir.ShallowSize = 0
+	for _, file := range dir.Files {
+		dir.ShallowSize += file.Size
+	}
+	dir.DeepSize = dir.ShallowSize
+	for _, child := range dir.Dirs {
+		FindTotalSizes(child)
+		dir.DeepSize += child.DeepSize
+	}
+}
+
+/*
+	io/fs view of the tree
+*/
+
+/*
+Tree serves the reconstructed filesystem as an fs.FS, so fs.WalkDir and
+friends work on it. Paths are the slash separated paths of io/fs, "." is
+the root. Only the sizes of files are known, reading one gives that many
+zero bytes. The Sys of a FileInfo is the *Directory or *File.
+*/
+type Tree struct {
+	Root *Directory
+}
+
+var (
+	_ fs.FS        = Tree{}
+	_ fs.ReadDirFS = Tree{}
+	_ fs.StatFS    = Tree{}
+)
+
+// the directory or file at name, one of the two is nil
+func (t Tree) lookup(op, name string) (*Directory, *File, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	dir := t.Root
+	if name == "." {
+		return dir, nil, nil
+	}
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if child, ok := dir.Dirs[part]; ok {
+			dir = child
+			continue
+		}
+		if file, ok := dir.Files[part]; ok && i == len(parts)-1 {
+			return nil, file, nil
+		}
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return dir, nil, nil
+}
+
+func (t Tree) Open(name string) (fs.File, error) {
+	dir, file, err := t.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		return &openFile{file: file}, nil
+	}
+	return &openDir{tree: t, name: name, dir: dir}, nil
+}
+
+func (t Tree) Stat(name string) (fs.FileInfo, error) {
+	dir, file, err := t.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		return fileInfo{file}, nil
+	}
+	return dirInfo{dir}, nil
+}
+
+// the entries of the directory sorted by name, as fs.ReadDir wants them
+func (t Tree) ReadDir(name string) ([]fs.DirEntry, error) {
+	dir, file, err := t.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if file != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	entries := []fs.DirEntry{}
+	for _, child := range dir.Dirs {
+		entries = append(entries, fs.FileInfoToDirEntry(dirInfo{child}))
+	}
+	for _, file := range dir.Files {
+		if _, clash := dir.Dirs[file.Name]; !clash {
+			entries = append(entries, fs.FileInfoToDirEntry(fileInfo{file}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+type dirInfo struct{ dir *Directory }
+
+func (i dirInfo) Name() string {
+	if i.dir.Parent == nil {
+		return "."
+	}
+	return i.dir.Name
 }
+func (i dirInfo) Size() int64        { return int64(i.dir.DeepSize) }
+func (i dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (i dirInfo) ModTime() time.Time { return time.Time{} }
+func (i dirInfo) IsDir() bool        { return true }
+func (i dirInfo) Sys() any           { return i.dir }
 
-func FindTotalSizes(curPath string, dirs map[string]*Directory) {
-	dir := dirs[curPath]
-	for _, childName := range dir.ChildrenNames {
-		childPath := PushOnDir(curPath, childName)
-		FindTotalSizes(childPath, dirs)
-		dir.DeepSize += dirs[childPath].DeepSize
+type fileInfo struct{ file *File }
+
+func (i fileInfo) Name() string       { return i.file.Name }
+func (i fileInfo) Size() int64        { return int64(i.file.Size) }
+func (i fileInfo) Mode() fs.FileMode  { return 0444 }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() any           { return i.file }
+
+// an open file reads as Size zero bytes
+type openFile struct {
+	file   *File
+	offset int
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return fileInfo{f.file}, nil }
+func (f *openFile) Close() error               { return nil }
+
+func (f *openFile) Read(b []byte) (int, error) {
+	if f.offset >= f.file.Size {
+		return 0, io.EOF
+	}
+	n := len(b)
+	if rest := f.file.Size - f.offset; n > rest {
+		n = rest
 	}
-	dir.DeepSize += dir.ShallowSize
+	for i := range b[:n] {
+		b[i] = 0
+	}
+	f.offset += n
+	return n, nil
 }
 
+type openDir struct {
+	tree    Tree
+	name    string
+	dir     *Directory
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return dirInfo{d.dir}, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+// fs.ReadDirFile, n <= 0 returns all remaining entries
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.tree.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+/*
+the path "-" or no path at all reads stdin
+*/
 func ReadLines(path string) []string {
-	file, err := os.Open(path)
-	Fatal(err)
-	defer file.Close()
+	var file io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		Fatal(err)
+		defer f.Close()
+		file = f
+	}
 	var lines []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -127,47 +505,337 @@ func ReadLines(path string) []string {
 	return lines
 }
 
-func SumDirsByTotalSize(dirs map[string]*Directory, totalSmallerThan int) int {
+func SumDirsByTotalSize(root *Directory, totalSmallerThan int) int {
 	sum := 0
-	for _, dir := range dirs {
+	root.Walk(func(dir *Directory) {
 		if dir.DeepSize < totalSmallerThan {
 			sum += dir.DeepSize
 		}
-	}
+	})
 	return sum
 }
 
 /*
-This function takes a map of Directory structs, how much disk space we have and how much we need to be free.
+This function takes the root Directory, how much disk space we have and how much we need to be free.
 We then find one directory which if deleted would free up enough space. We choose the smallest possible directory.
 We return the total size of that directory.
-We start by iterating over the map and storing the smallest directory size that would be enough.
+We start by walking the tree and storing the smallest directory size that would be enough.
 */
-func FindSmallestDir(dirs map[string]*Directory, diskSize int, neededSpace int) int {
+func FindSmallestDir(root *Directory, diskSize int, neededSpace int) int {
 	minSize := diskSize
 	// total disk space used
-	usedSize := dirs["/"].DeepSize
+	usedSize := root.DeepSize
 	freeSize := diskSize - usedSize
-	for _, dir := range dirs {
+	root.Walk(func(dir *Directory) {
 		// find the directory which if deleted would free up enough space
 		if freeSize+dir.DeepSize >= neededSpace {
 			if dir.DeepSize < minSize {
 				minSize = dir.DeepSize
 			}
 		}
-	}
+	})
 	return minSize
 }
 
 /*
-Read input file into lines from os.Argv[1]
+	du style report
+*/
+
+// a size the way du -h prints it, 1024 based: 584, 4.0K, 12K, 1.5M
+func HumanSize(size int) string {
+	if size < 1024 {
+		return strconv.Itoa(size)
+	}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < 4 {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, "BKMGT"[unit])
+	}
+	return fmt.Sprintf("%.0f%c", value, "BKMGT"[unit])
+}
+
+// the sub directories, largest DeepSize first and by name when equal
+func (d *Directory) BySize() []*Directory {
+	dirs := []*Directory{}
+	for _, name := range sortedKeys(d.Dirs) {
+		dirs = append(dirs, d.Dirs[name])
+	}
+	sort.SliceStable(dirs, func(i, j int) bool { return dirs[i].DeepSize > dirs[j].DeepSize })
+	return dirs
+}
+
+/*
+print the directory tree like du, every directory with its total size and
+its share of the directory above it, the largest first. maxDepth limits
+how far below root it goes, negative means no limit. FindTotalSizes must
+have run.
+*/
+func Report(w io.Writer, root *Directory, maxDepth int) {
+	var show func(dir *Directory, depth int)
+	show = func(dir *Directory, depth int) {
+		share := 100.0
+		if dir.Parent != nil && dir.Parent.DeepSize > 0 {
+			share = 100 * float64(dir.DeepSize) / float64(dir.Parent.DeepSize)
+		}
+		name := dir.Name + "/"
+		if depth == 0 {
+			name = dir.Fullpath()
+		}
+		fmt.Fprintf(w, "%6s %5.1f%%  %s%s\n", HumanSize(dir.DeepSize), share, strings.Repeat("  ", depth), name)
+		if maxDepth >= 0 && depth >= maxDepth {
+			return
+		}
+		for _, child := range dir.BySize() {
+			show(child, depth+1)
+		}
+	}
+	show(root, 0)
+}
+
+/*
+	deletion planner
+*/
+
+/*
+the most that k directories below dir can free, for every k. none of the
+directories may contain another, dir itself counts when self is true.
+*/
+func mostFreed(dir *Directory, self bool) []int {
+	most := []int{0}
+	for _, child := range dir.Dirs {
+		sub := mostFreed(child, true)
+		merged := make([]int, len(most)+len(sub)-1)
+		for i := range merged {
+			merged[i] = -1
+		}
+		for i, a := range most {
+			for j, b := range sub {
+				merged[i+j] = maxInt(merged[i+j], a+b)
+			}
+		}
+		most = merged
+	}
+	if self {
+		if len(most) == 1 {
+			most = append(most, 0)
+		}
+		most[1] = maxInt(most[1], dir.DeepSize)
+	}
+	return most
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// one of the two contains the other
+func overlap(a, b *Directory) bool {
+	for d := a; d != nil; d = d.Parent {
+		if d == b {
+			return true
+		}
+	}
+	for d := b; d != nil; d = d.Parent {
+		if d == a {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Plan finds the directories to delete to free at least target. It takes as
+few directories as possible, none of them inside another, and of those
+plans the one that frees the least. / itself is never deleted. With one
+directory this is the answer of part 2. FindTotalSizes must have run.
+
+The fewest directories come from mostFreed, then a search over the
+directories, largest first, finds the smallest total for that many.
+*/
+func Plan(root *Directory, target int) ([]*Directory, error) {
+	if target <= 0 {
+		return []*Directory{}, nil
+	}
+	most := mostFreed(root, false)
+	count := 0
+	for count < len(most) && most[count] < target {
+		count++
+	}
+	if count == len(most) {
+		largest := 0
+		for _, freed := range most {
+			largest = maxInt(largest, freed)
+		}
+		return nil, fmt.Errorf("deleting directories frees at most %d, not %d, the %d in files directly under / can never be freed", largest, target, root.ShallowSize)
+	}
+
+	candidates := []*Directory{}
+	root.Walk(func(dir *Directory) {
+		if dir != root {
+			candidates = append(candidates, dir)
+		}
+	})
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].DeepSize > candidates[j].DeepSize })
+
+	var best []*Directory
+	bestFreed := -1
+	chosen := []*Directory{}
+	var search func(start, freed int)
+	search = func(start, freed int) {
+		if bestFreed >= 0 && freed >= bestFreed {
+			return
+		}
+		left := count - len(chosen)
+		if left == 0 {
+			if freed >= target {
+				best = append([]*Directory{}, chosen...)
+				bestFreed = freed
+			}
+			return
+		}
+		for i := start; i < len(candidates); i++ {
+			// even the largest ones left are not enough
+			reach := freed
+			for j := i; j < i+left && j < len(candidates); j++ {
+				reach += candidates[j].DeepSize
+			}
+			if reach < target {
+				return
+			}
+			dir := candidates[i]
+			clash := false
+			for _, other := range chosen {
+				if overlap(dir, other) {
+					clash = true
+					break
+				}
+			}
+			if clash {
+				continue
+			}
+			chosen = append(chosen, dir)
+			search(i+1, freed+dir.DeepSize)
+			chosen = chosen[:len(chosen)-1]
+		}
+	}
+	search(0, 0)
+	return best, nil
+}
+
+func PrintPlan(w io.Writer, plan []*Directory, target int) {
+	freed := 0
+	for _, dir := range plan {
+		freed += dir.DeepSize
+	}
+	fmt.Fprintf(w, "Plan: %d (%s) to free, %d (%s) freed by deleting:\n", target, HumanSize(target), freed, HumanSize(freed))
+	for _, dir := range plan {
+		fmt.Fprintf(w, "%12d %6s  %s\n", dir.DeepSize, HumanSize(dir.DeepSize), dir.Fullpath())
+	}
+}
+
+/*
+the sizes the puzzle works with, the example uses the same disk as the
+real input. a json file given with -config overrides the defaults, and
+flags given on the command line override that.
+*/
+type Params struct {
+	DiskSize      int `json:"disk_size"`
+	NeededSpace   int `json:"needed_space"`
+	SmallDirLimit int `json:"small_dir_limit"`
+}
+
+var DefaultParams = Params{
+	DiskSize:      70000000,
+	NeededSpace:   30000000,
+	SmallDirLimit: 100000,
+}
+
+var (
+	configPath        = flag.String("config", "", "json file with puzzle parameters")
+	diskSizeFlag      = flag.Int("disk-size", DefaultParams.DiskSize, "total size of the disk")
+	neededSpaceFlag   = flag.Int("needed-space", DefaultParams.NeededSpace, "free space the update needs")
+	smallDirLimitFlag = flag.Int("small-dir-limit", DefaultParams.SmallDirLimit, "part 1 sums directories smaller than this")
+
+	list = flag.Bool("list", false, "also walk the reconstructed filesystem with fs.WalkDir and print every path, directories with their total size")
+
+	duReport = flag.Bool("du", false, "also print the directory tree with human readable sizes, largest first")
+	maxDepth = flag.Int("depth", -1, "how many levels below / the -du report goes, negative for all")
+	plan     = flag.Bool("plan", false, "also plan the fewest directories to delete to free -free")
+	freeFlag = flag.Int("free", 0, "space the -plan has to free, by default what the update still needs")
+)
+
+// the defaults with the config file and the flags given on the command line applied
+func LoadParams() Params {
+	params := DefaultParams
+	if *configPath != "" {
+		file, err := os.Open(*configPath)
+		Fatal(err)
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			Fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "disk-size":
+			params.DiskSize = *diskSizeFlag
+		case "needed-space":
+			params.NeededSpace = *neededSpaceFlag
+		case "small-dir-limit":
+			params.SmallDirLimit = *smallDirLimitFlag
+		}
+	})
+	return params
+}
+
+/*
+Read input file into lines from flag.Arg(0)
 Feed the lines to ProcessCommands
 */
 func main() {
-	lines := ReadLines(os.Args[1])
-	dirs := ProcessCommands(lines)
-	FindTotalSizes("/", dirs)
-	fmt.Println("Part1:", SumDirsByTotalSize(dirs, 100000))
-	fmt.Println("Part2:", FindSmallestDir(dirs, 70000000, 30000000))
+	flag.Parse()
+	params := LoadParams()
+	lines := ReadLines(flag.Arg(0))
+	root, err := ProcessCommands(lines)
+	Fatal(err)
+	FindTotalSizes(root)
+	fmt.Println("Part1:", SumDirsByTotalSize(root, params.SmallDirLimit))
+	fmt.Println("Part2:", FindSmallestDir(root, params.DiskSize, params.NeededSpace))
+
+	if *list {
+		// the reconstructed filesystem through io/fs
+		Fatal(fs.WalkDir(Tree{root}, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s %12d %s\n", info.Mode(), info.Size(), name)
+			return nil
+		}))
+	}
+	if *duReport {
+		Report(os.Stdout, root, *maxDepth)
+	}
+	if *plan {
+		target := *freeFlag
+		if target == 0 {
+			target = params.NeededSpace - (params.DiskSize - root.DeepSize)
+		}
+		dirs, err := Plan(root, target)
+		Fatal(err)
+		PrintPlan(os.Stdout, dirs, target)
+	}
 
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -35,11 +37,16 @@ x and y are the coordinates of the point
 visibleCount is 0 by default
 treeHeight is the character ('0'-'9') converted to int using Atoi
 scenicScore is 1 by default
+the filename "-" or no filename at all reads stdin
 */
 func readInFile(filename string) [][]Point {
-	file, err := os.Open(filename)
-	Fatal(err)
-	defer file.Close()
+	var file io.Reader = os.Stdin
+	if filename != "" && filename != "-" {
+		f, err := os.Open(filename)
+		Fatal(err)
+		defer f.Close()
+		file = f
+	}
 
 	var points [][]Point
 
@@ -152,7 +159,7 @@ func findHighestScenicScore(points [][]Point) int {
 }
 
 /*
-assign Argc[1] to filename
+assign flag.Arg(0) to filename
 call readInFile and getDimensions
 assert the dimensions are equal
 call checkAllDirections and countVisible
@@ -160,7 +167,8 @@ print the result as Part1: <result>
 print the result of findHighestScenicScore as Part2: <result>
 */
 func main() {
-	filename := os.Args[1]
+	flag.Parse()
+	filename := flag.Arg(0)
 	points := readInFile(filename)
 	width, height := getDimensions(points)
 	assertEqual(width, height)
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 )
@@ -32,49 +34,49 @@ type Move struct {
 }
 
 /*
-read a file from argument 'filename' and split it into lines
-remove the last empty line
+open the input. the path "-" or no path at all reads stdin.
 */
-func readLines(filename string) []string {
-	file, err := os.Open(filename)
-	Fatal(err)
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+func OpenInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
 	}
-	Fatal(scanner.Err())
-	return lines
+	return os.Open(path)
 }
 
 /*
-moves are lines of the form "R 4" or "D 21"
+a move is a line of the form "R 4" or "D 21"
 the direction is the first thing, and the distance is the second thing
 there is a space in the middle
 parse the number using strconv.Atoi
 */
-func parseMoves(lines []string) []Move {
-	var moves []Move
-	for _, line := range lines {
-		var move Move
-		switch line[0] {
-		case 'U':
-			move.direction = UP
-		case 'D':
-			move.direction = DOWN
-		case 'L':
-			move.direction = LEFT
-		case 'R':
-			move.direction = RIGHT
-		}
-		distance, err := strconv.Atoi(line[2:])
-		Fatal(err)
-		move.distance = distance
-		moves = append(moves, move)
+func parseMove(line string) Move {
+	var move Move
+	switch line[0] {
+	case 'U':
+		move.direction = UP
+	case 'D':
+		move.direction = DOWN
+	case 'L':
+		move.direction = LEFT
+	case 'R':
+		move.direction = RIGHT
+	}
+	distance, err := strconv.Atoi(line[2:])
+	Fatal(err)
+	move.distance = distance
+	return move
+}
+
+/*
+read moves from r one line at a time and hand each one to handle.
+the moves are not collected, so the input can be any size.
+*/
+func readMoves(r io.Reader, handle func(Move)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		handle(parseMove(scanner.Text()))
 	}
-	return moves
+	Fatal(scanner.Err())
 }
 
 /*
@@ -100,64 +102,67 @@ func neighbors(x, y, sideLen int) [][2]int {
 }
 
 /*
-the arguments are a list of 'moves' and the number of moving things in total.
-the 'track' argument signifies which(nth) objects unique visited locations count
-we return as the result. collect the unique locations visited by that
-item locations in a map.
+a rope is all the moving things in total.
+the 'track' field signifies which(nth) objects unique visited locations count
+we collect. collect the unique locations visited by that item in a map.
+*/
+type Rope struct {
+	tails   [][2]int
+	track   int
+	visited map[[2]int]bool
+}
 
-allocate all the moving things as coordinates into an array.
-loop over the moves, but only the first item will move like the 'move' says.
+// allocate all the moving things as coordinates into an array.
+func NewRope(tailsCount int, track int) *Rope {
+	rope := &Rope{track: track, visited: make(map[[2]int]bool)}
+	for i := 0; i < tailsCount; i++ {
+		rope.tails = append(rope.tails, [2]int{0, 0})
+	}
+	rope.visited[rope.tails[track]] = true
+	return rope
+}
+
+/*
+only the first item will move like the 'move' says.
 then move all following moving things, by following these rules. the first one does not do this:
  1. call neighbors(my_x, my_y, 5)
  2. check if the previous item coordinate is one of those
  3. if they do set my current position as the result of findNextPosition()
 */
-func runMoves(moves []Move, tailsCount int, track int) int {
-	// allocate all the moving things as coordinates into an array.
-	var tails [][2]int
-	for i := 0; i < tailsCount; i++ {
-		tails = append(tails, [2]int{0, 0})
-	}
-
-	// collect the unique locations visited by that item locations in a map.
-	visited := make(map[[2]int]bool)
-	visited[tails[track]] = true
-
-	// loop over the moves, but only the first item will move like the 'move' says.
-	// then move all following moving things, by following these rules. the first one does not do this:
-	for _, move := range moves {
-		// 1. call neighbors(my_x, my_y, 5)
-		// 2. check if the previous item coordinate is one of those
-		// 3. if they do set my current position as the result of findNextPosition()
-		for step := 0; step < move.distance; step++ {
-			for i := 0; i < tailsCount; i++ {
-				if i == 0 {
-					switch move.direction {
-					case UP:
-						tails[i][1] += 1
-					case DOWN:
-						tails[i][1] -= 1
-					case LEFT:
-						tails[i][0] -= 1
-					case RIGHT:
-						tails[i][0] += 1
-					}
-				} else {
-					neighbors := neighbors(tails[i][0], tails[i][1], 5)
-					for _, neighbor := range neighbors {
-						if neighbor == tails[i-1] {
-							tails[i] = findNextPosition(tails[i-1], tails[i])
-							break
-						}
-					}
+func (r *Rope) Move(move Move) {
+	tails := r.tails
+	for step := 0; step < move.distance; step++ {
+		for i := 0; i < len(tails); i++ {
+			if i == 0 {
+				switch move.direction {
+				case UP:
+					tails[i][1] += 1
+				case DOWN:
+					tails[i][1] -= 1
+				case LEFT:
+					tails[i][0] -= 1
+				case RIGHT:
+					tails[i][0] += 1
 				}
-				if i == track {
-					visited[tails[i]] = true
+			} else {
+				neighbors := neighbors(tails[i][0], tails[i][1], 5)
+				for _, neighbor := range neighbors {
+					if neighbor == tails[i-1] {
+						tails[i] = findNextPosition(tails[i-1], tails[i])
+						break
+					}
 				}
 			}
+			if i == r.track {
+				r.visited[tails[i]] = true
+			}
 		}
 	}
-	return len(visited)
+}
+
+// the number of unique locations the tracked item has visited
+func (r *Rope) Visited() int {
+	return len(r.visited)
 }
 
 /*
@@ -193,13 +198,21 @@ func findNextPosition(head, tail [2]int) [2]int {
 }
 
 /*
-read file from os.Args[1] using readLines. parse the moves using parseMoves().
-run the moves with 2,1 and print the result as Part1:
-run the moves with 10,9 and print the result as Part2:
+read moves from flag.Arg(0) using readMoves, stdin when it is "-" or missing.
+feed every move to a rope of 2,1 and print the result as Part1:
+feed every move to a rope of 10,9 and print the result as Part2:
 */
 func main() {
-	lines := readLines(os.Args[1])
-	moves := parseMoves(lines)
-	fmt.Println("Part1:", runMoves(moves, 2, 1))
-	fmt.Println("Part2:", runMoves(moves, 10, 9))
+	flag.Parse()
+	input, err := OpenInput(flag.Arg(0))
+	Fatal(err)
+	defer input.Close()
+	part1 := NewRope(2, 1)
+	part2 := NewRope(10, 9)
+	readMoves(input, func(move Move) {
+		part1.Move(move)
+		part2.Move(move)
+	})
+	fmt.Println("Part1:", part1.Visited())
+	fmt.Println("Part2:", part2.Visited())
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -12,30 +14,26 @@ func Fail(err error) {
 	}
 }
 
-func ReadFileToLines(filename string) []string {
-	file, err := os.Open(filename)
-	Fail(err)
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+/*
+open the input file. the path "-" or no path at all reads stdin.
+*/
+func OpenInput(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
 	}
-	Fail(scanner.Err())
-	return lines
+	return os.Open(path)
 }
 
 /*
-this function takes a string array of lines and returns the summed signal
-strengths.
+this function reads lines from r one at a time and returns the summed signal
+strengths. the lines are not collected, so the program can be any size.
 parse the instructions:
 
 	"addx <value>" // value can be negative or 0. takes 2 cycles to run.
 	"noop" // takes 1 cycle to run.
 
 have a register X and a totalCycles counter.
-loop over all lines. inside the loop parse the instruction.
+loop over all lines as they are read. inside the loop parse the instruction.
 if we have an addx instruction, store the 'cycles' value of 2 and the Value.
 if we have an noop instruction, store the 'cycles' value of 1 and the Value 0.
 
@@ -43,11 +41,13 @@ inside the 'cycles' loop:
 every cycle run a check for (totalCycles + 20) % 40 == 0. if that modulus operation is true, add (totalCycles * X) to get a signalStrength and add it to sumSignalStength.
 return sumSignalStrength.
 */
-func RunLines(lines []string) int {
+func RunLines(r io.Reader) int {
 	sumSignalStrength := 0
 	X := 1
 	totalCycles := 0
-	for _, line := range lines {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
 		cycles := 1
 		value := 0
 		if line[0:4] == "addx" {
@@ -63,6 +63,7 @@ func RunLines(lines []string) int {
 		}
 		X += value
 	}
+	Fail(scanner.Err())
 	return sumSignalStrength
 }
 
@@ -82,11 +83,14 @@ func DrawPixel(cycle int, X int) {
 }
 
 /*
-parse lines of file Argv[1] and print the result as Part1:
+parse lines of file flag.Arg(0), or stdin, and print the result as Part1:
 */
 func main() {
-	lines := ReadFileToLines(os.Args[1])
-	result := RunLines(lines)
+	flag.Parse()
+	input, err := OpenInput(flag.Arg(0))
+	Fail(err)
+	defer input.Close()
+	result := RunLines(input)
 	println("Part1:", result)
 	// Part2 is printed first, it's inlined
 }
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"os"
 	"sort"
@@ -11,12 +15,28 @@ import (
 	"strings"
 )
 
+var (
+	checkpointEvery = flag.Int("checkpoint-every", 0, "write the simulation state every N rounds, 0 disables checkpoints")
+	checkpointPath  = flag.String("checkpoint", "day11.checkpoint", "path of the checkpoint file")
+	resume          = flag.Bool("resume", false, "continue the simulation from the checkpoint file")
+)
+
 func Fatal(err error) {
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type Op struct {
 	Type  rune
 	Value *int
@@ -66,10 +86,10 @@ monkey goes over all items.
 calculate operation outcome for each line.
 'old' means the current item, and the possible operations are add and multiply.
 it can be 'old + x' or 'old + old'.
-then divide that number by 3 and round down.
+then divide that number by relief (3 in the task) and round down.
 check the TestDivisibleBy number. if it's divisible, throw to ThrowToTrue, else throw to ThrowToFalse.
 */
-func (m *Monkey) MonkeyTurnP1(monkeys *[]Monkey) {
+func (m *Monkey) MonkeyTurnP1(monkeys *[]Monkey, relief int) {
 	// for each item
 	for _, item := range m.Items {
 		(*m).InspectionCount++
@@ -88,8 +108,8 @@ func (m *Monkey) MonkeyTurnP1(monkeys *[]Monkey) {
 		case "*":
 			newValue = varA * varB
 		}
-		// divide by 3 and round down
-		newValue = newValue / 3
+		// divide by relief and round down
+		newValue = newValue / relief
 		// check the TestDivisibleBy number
 		item.Value = newValue
 		if newValue%m.TestDivisibleBy == 0 {
@@ -234,21 +254,174 @@ func ParseMonkeysString(s string) ([]Monkey, error) {
 	return monkeys, nil
 }
 
+/*
+checkpoints are json files with a version number, so that a file written by
+an older build is refused instead of being misread.
+Part tells which simulation the state belongs to, Round is the number of
+rounds already played. Params and Input, the InputHash of the puzzle input,
+tell which run it belongs to, a checkpoint of another run is refused.
+*/
+const CheckpointVersion = 2
+
+type Checkpoint struct {
+	Version int
+	Params  Params
+	Input   string
+	Part    int
+	Round   int
+	Monkeys []Monkey
+}
+
+// identifies the input a checkpoint was written for
+func InputHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// an error when the checkpoint was written with other parameters or input
+func (cp Checkpoint) Check(params Params, input string) error {
+	if cp.Params != params {
+		return fmt.Errorf("checkpoint was written with %+v, not %+v", cp.Params, params)
+	}
+	if cp.Input != input {
+		return errors.New("checkpoint was written for another input")
+	}
+	return nil
+}
+
+/*
+write the checkpoint to a temporary file first and rename it over the
+old one, so a crash while writing does not destroy the previous checkpoint
+*/
+func SaveCheckpoint(path string, cp Checkpoint) error {
+	cp.Version = CheckpointVersion
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("LoadCheckpoint: %s: %w", path, err)
+	}
+	if cp.Version != CheckpointVersion {
+		return cp, fmt.Errorf("LoadCheckpoint: %s: unsupported version %d", path, cp.Version)
+	}
+	return cp, nil
+}
+
+/*
+play the rounds of part 1 or part 2.
+if -resume is set and the checkpoint belongs to this part, continue from it.
+if -checkpoint-every is set, save the state after every N rounds.
+input is the InputHash of the puzzle input.
+*/
+func RunRounds(monkeys []Monkey, part int, params Params, input string) []Monkey {
+	rounds := params.Part1Rounds
+	if part == 2 {
+		rounds = params.Part2Rounds
+	}
+	startRound := 0
+	if *resume {
+		cp, err := LoadCheckpoint(*checkpointPath)
+		Fatal(err)
+		Fatal(cp.Check(params, input))
+		if cp.Part == part {
+			monkeys = cp.Monkeys
+			startRound = cp.Round
+			fmt.Printf("resuming part %d from round %d\n", part, startRound)
+		}
+	}
+	for round := startRound; round < rounds; round++ {
+		for i, monkey := range monkeys {
+			if part == 1 {
+				monkey.MonkeyTurnP1(&monkeys, params.Relief)
+			} else {
+				monkey.MonkeyTurnP2(&monkeys)
+			}
+			monkeys[i] = monkey
+		}
+		if *checkpointEvery > 0 && (round+1)%*checkpointEvery == 0 {
+			Fatal(SaveCheckpoint(*checkpointPath, Checkpoint{Params: params, Input: input, Part: part, Round: round + 1, Monkeys: monkeys}))
+		}
+	}
+	return monkeys
+}
+
+/*
+rounds and relief of the game. the example is played by the same rules as
+the real input, so there is one set of defaults. -config names a json file
+that overrides them, flags given on the command line win over both.
+*/
+type Params struct {
+	Part1Rounds int `json:"part1_rounds"`
+	Part2Rounds int `json:"part2_rounds"`
+	Relief      int `json:"relief"`
+}
+
+var DefaultParams = Params{
+	Part1Rounds: 20,
+	Part2Rounds: 10000,
+	Relief:      3,
+}
+
+var (
+	configPath      = flag.String("config", "", "json file with puzzle parameters")
+	part1RoundsFlag = flag.Int("part1-rounds", DefaultParams.Part1Rounds, "rounds played in part 1")
+	part2RoundsFlag = flag.Int("part2-rounds", DefaultParams.Part2Rounds, "rounds played in part 2")
+	reliefFlag      = flag.Int("relief", DefaultParams.Relief, "part 1 worry levels are divided by this after each inspection")
+)
+
+// the defaults with the config file and the flags given on the command line applied
+func LoadParams() Params {
+	params := DefaultParams
+	if *configPath != "" {
+		file, err := os.Open(*configPath)
+		Fatal(err)
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			Fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "part1-rounds":
+			params.Part1Rounds = *part1RoundsFlag
+		case "part2-rounds":
+			params.Part2Rounds = *part2RoundsFlag
+		case "relief":
+			params.Relief = *reliefFlag
+		}
+	})
+	return params
+}
+
 func main() {
-	// read in a file from path in os.Args[1]
-	file, err := ioutil.ReadFile(os.Args[1])
+	flag.Parse()
+	params := LoadParams()
+	// read in a file from path in flag.Arg(0), or stdin
+	file, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// parse the file
 	// == Part 1 ==
 	monkeys, err := ParseMonkeysString(string(file))
 	Fatal(err)
 	// run the simulation for 20 rounds
-	for i := 0; i < 20; i++ {
-		for i, monkey := range monkeys {
-			monkey.MonkeyTurnP1(&monkeys)
-			monkeys[i] = monkey
-		}
-	}
+	input := InputHash(file)
+	monkeys = RunRounds(monkeys, 1, params, input)
 	// sort monkeys by highest InspectionCount
 	// multiply top 2 monkey inspection counts and Print as Part1
 	sort.Slice(monkeys, func(i, j int) bool {
@@ -260,12 +433,7 @@ func main() {
 	monkeys, err = ParseMonkeysString(string(file))
 	Fatal(err)
 	// run the simulation for 10000 rounds
-	for i := 0; i < 10000; i++ {
-		for i, monkey := range monkeys {
-			monkey.MonkeyTurnP2(&monkeys)
-			monkeys[i] = monkey
-		}
-	}
+	monkeys = RunRounds(monkeys, 2, params, input)
 	// sort monkeys by highest InspectionCount
 	// multiply top 2 monkey inspection counts and Print as Part2
 	sort.Slice(monkeys, func(i, j int) bool {
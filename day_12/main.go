@@ -1,17 +1,32 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
+var explain = flag.Bool("explain", false, "print the cells of the shortest paths behind the answers")
+
 func Fatal(err error) {
 	if err != nil {
 		panic(err)
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type Tuple[T int] struct {
 	x, y T
 }
@@ -110,11 +125,71 @@ func FindShortestPath(from Tuple[int], at Tuple[int], stepsTaken int, tiles [][]
 }
 
 /*
-read input from file at os.ReadFile(os.Args[1])
+walk back from E to 'from' after FindShortestPath has filled in shortestPath.
+each step goes to a neighbor that is one step closer to the start and that
+we could have climbed from. the start tile itself may have been overwritten
+by a longer walk, so we stop at the tile one step away from it.
+*/
+func TracePath(tiles [][]Tile, from Tuple[int]) []Tuple[int] {
+	var at Tuple[int]
+	for y, row := range tiles {
+		for x, tile := range row {
+			if tile.isEnd {
+				at = Tuple[int]{x, y}
+			}
+		}
+	}
+	path := []Tuple[int]{at}
+	for tiles[at.y][at.x].shortestPath > 1 {
+		steps := tiles[at.y][at.x].shortestPath
+		for _, next := range []Tuple[int]{
+			{at.x - 1, at.y},
+			{at.x + 1, at.y},
+			{at.x, at.y - 1},
+			{at.x, at.y + 1},
+		} {
+			if next.x < 0 || next.y < 0 || next.x >= len(tiles[0]) || next.y >= len(tiles) {
+				continue
+			}
+			tile := tiles[next.y][next.x]
+			if tile.shortestPath == steps-1 && tiles[at.y][at.x].letter <= tile.letter+1 {
+				at = next
+				break
+			}
+		}
+		if tiles[at.y][at.x].shortestPath == steps {
+			panic(fmt.Sprintf("TracePath: stuck at %v", at))
+		}
+		path = append(path, at)
+	}
+	path = append(path, from)
+	// reverse the path so it goes from start to end
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+type Explanation struct {
+	Part1Path [][2]int `json:"part1_path"`
+	Part2Path [][2]int `json:"part2_path"`
+}
+
+func PathCells(path []Tuple[int]) [][2]int {
+	cells := [][2]int{}
+	for _, cell := range path {
+		cells = append(cells, [2]int{cell.x, cell.y})
+	}
+	return cells
+}
+
+/*
+read input from the file at flag.Arg(0), or stdin, with ReadInput
 parse the file and then find the shortest path from S to E
 */
 func main() {
-	input, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	input, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	tiles := ParseMap(string(input))
 	// find start tile
@@ -129,18 +204,34 @@ func main() {
 	// print starting location
 	shortest := FindShortestPath(start, start, 0, tiles)
 	fmt.Println("Part1:", shortest)
+	var explanation Explanation
+	if *explain {
+		explanation.Part1Path = PathCells(TracePath(tiles, start))
+	}
 
 	// find each 'a' and find the shortest path from there to E
 	shortestAPath := -1
+	var shortestAStart Tuple[int]
 	for y, row := range tiles {
 		for x, tile := range row {
 			if tile.letter == 'a' {
 				shortest := FindShortestPath(Tuple[int]{x, y}, Tuple[int]{x, y}, 0, tiles)
 				if shortest > 0 && (shortestAPath == -1 || shortest < shortestAPath) {
 					shortestAPath = shortest
+					shortestAStart = Tuple[int]{x, y}
 				}
 			}
 		}
 	}
 	fmt.Println("Part2:", shortestAPath)
+
+	if *explain {
+		// the searches above share tiles, so walk the best start again on a fresh map
+		tiles = ParseMap(string(input))
+		FindShortestPath(shortestAStart, shortestAStart, 0, tiles)
+		explanation.Part2Path = PathCells(TracePath(tiles, shortestAStart))
+		data, err := json.Marshal(explanation)
+		Fatal(err)
+		fmt.Println(string(data))
+	}
 }
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -13,6 +15,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 func Max[T int](a, b T) T {
 	if a > b {
 		return a
@@ -152,8 +164,9 @@ and print each output line from Extract
 Handwritten
 */
 func main() {
-	fname := os.Args[1]
-	data, err := os.ReadFile(fname)
+	flag.Parse()
+	fname := flag.Arg(0)
+	data, err := ReadInput(fname)
 	Fatal(err)
 	scoreP1 := 0
 	scoreP2_2 := 1 // start at 1 because ths is the first index
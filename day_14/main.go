@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -16,6 +18,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 func Abs[T int](x T) T {
 	if x < 0 {
 		return -x
@@ -257,7 +269,7 @@ func (p *PlayField) Bury() {
 
 /*
 create a new PlayField.
-parse lines from Args[1] using os.ReadFile.
+parse lines from flag.Arg(0) using ReadInput.
 parse the stone lines.
 add a new emitter at {500, 0}.
 add new falling objects every time there are no falling objects.
@@ -265,6 +277,7 @@ move the falling objects until the hitAbyss flag is set.
 print out the count of atRest objects as "Part1:"
 */
 func main() {
+	flag.Parse()
 	// create a new PlayField
 	var playField PlayField = PlayField{
 		emitter:      Pos{500, 0},
@@ -276,8 +289,8 @@ func main() {
 		floor:        false,
 		hitAbyss:     false,
 	}
-	// parse lines from Args[1] using os.ReadFile
-	lines, err := os.ReadFile(os.Args[1])
+	// parse lines from flag.Arg(0) using ReadInput
+	lines, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// parse the stone lines
 	for _, line := range strings.Split(string(lines), "\n") {
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+var explain = flag.Bool("explain", false, "print the hidden beacon location behind the answers")
+
 // utility
 
 func Fatal(err error) {
@@ -15,6 +21,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 func Abs(x int) int {
 	if x < 0 {
 		return -x
@@ -57,12 +73,12 @@ func ParseLine(line string) Fact {
 }
 
 /*
-read a file using os.ReadFile from Args[1].
+read a file using os.ReadFile from flag.Arg(0).
 parse each line using ParseLine into a Fact.
 return a list of facts.
 */
 func ReadFacts() []Fact {
-	file, err := os.ReadFile(os.Args[1])
+	file, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	lines := strings.Split(string(file), "\n")
 	facts := make([]Fact, len(lines)-1) // last line is empty
@@ -204,37 +220,133 @@ func FindFactPair(facts []Fact) (Fact, Fact, Fact, Fact) {
 	panic(fmt.Sprintf("no fact pair found: %v", facts))
 }
 
+/*
+the row and bound differ between the example and the real input.
+RealParams are used by default, ExampleParams with -example or when the
+input file name starts with "example", which can not be seen when the
+input comes from stdin. a json file given with -config overrides them, and
+flags given on the command line override that.
+*/
+type Params struct {
+	Row   int `json:"row"`
+	Bound int `json:"bound"`
+}
+
+var RealParams = Params{
+	Row:   2000000,
+	Bound: 4000000,
+}
+
+var ExampleParams = Params{
+	Row:   10,
+	Bound: 20,
+}
+
+var (
+	configPath = flag.String("config", "", "json file with puzzle parameters")
+	example    = flag.Bool("example", false, "start from the example parameters, needed when the example comes from stdin")
+	rowFlag    = flag.Int("row", RealParams.Row, "row where part 1 counts positions without a beacon")
+	boundFlag  = flag.Int("bound", RealParams.Bound, "part 2 searches x and y in 0..bound")
+)
+
+/*
+pick the defaults for the input, then apply the config file and the flags
+that were given on the command line
+*/
+func LoadParams(inputPath string) Params {
+	params := RealParams
+	if *example || strings.HasPrefix(filepath.Base(inputPath), "example") {
+		params = ExampleParams
+	}
+	if *configPath != "" {
+		file, err := os.Open(*configPath)
+		Fatal(err)
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			Fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "row":
+			params.Row = *rowFlag
+		case "bound":
+			params.Bound = *boundFlag
+		}
+	})
+	return params
+}
+
+/*
+the hidden beacon, the four sensors whose edges pin it down, and the
+distance from the beacon to every sensor compared to that sensors range
+*/
+type SensorCheck struct {
+	Sensor   Point `json:"sensor"`
+	Range    int   `json:"range"`
+	Distance int   `json:"distance"`
+}
+
+type Explanation struct {
+	Beacon          Point         `json:"beacon"`
+	TuningFrequency int           `json:"tuning_frequency"`
+	BoundingFacts   []Fact        `json:"bounding_facts"`
+	Sensors         []SensorCheck `json:"sensors"`
+}
+
 // 4736899 is low
 // 4347487 is low
 // 4347486 is low
 func main() {
+	flag.Parse()
+	params := LoadParams(flag.Arg(0))
 	facts := ReadFacts()
 	furthest := FurthestDistance(facts)
 	leftmost := LeftmostPOI(facts, furthest)
 	rightmost := RightmostPOI(facts, furthest)
-	countP1 := CountClean(facts, 2000000, leftmost.X, rightmost.X)
+	countP1 := CountClean(facts, params.Row, leftmost.X, rightmost.X)
 	fmt.Printf("Part1: %d\n", countP1)
 
 	// part 2
-	_, _, _, factD := FindFactPair(facts)
+	factA, factB, factC, factD := FindFactPair(facts)
 
 	// sensor D manhattan distance to its sensor
 	manD := ManhattanDistance(factD.Sensor, factD.Beacon)
 	// move to highest point on D, then +1
 	topD := Point{factD.Sensor.X, factD.Sensor.Y - manD - 1}
 	// start moving down-right from topD and check IsInvisible
-	// until we find a point that is invisible
-	for {
-		if IsInvisible(facts, topD) {
-			if topD.X >= 0 && topD.X <= 4000000 && topD.Y >= 0 && topD.Y <= 4000000 {
-				break
-			} else {
-				break
-			}
+	// until we find a point that is invisible and inside the bound.
+	// the edge is only manD+2 points long, past that there is nothing to find
+	for step := 0; ; step++ {
+		if step > manD+1 {
+			panic(fmt.Sprintf("no hidden beacon within 0..%d next to sensor %v", params.Bound, factD.Sensor))
+		}
+		if IsInvisible(facts, topD) && topD.X >= 0 && topD.X <= params.Bound && topD.Y >= 0 && topD.Y <= params.Bound {
+			break
 		}
 		topD.X++
 		topD.Y++
 	}
 	// multiply X by 4000000 and add Y
 	fmt.Printf("Part2: %d\n", topD.X*4000000+topD.Y)
+
+	if *explain {
+		explanation := Explanation{
+			Beacon:          topD,
+			TuningFrequency: topD.X*4000000 + topD.Y,
+			BoundingFacts:   []Fact{factA, factB, factC, factD},
+		}
+		for _, fact := range facts {
+			explanation.Sensors = append(explanation.Sensors, SensorCheck{
+				Sensor:   fact.Sensor,
+				Range:    ManhattanDistance(fact.Sensor, fact.Beacon),
+				Distance: ManhattanDistance(fact.Sensor, topD),
+			})
+		}
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		Fatal(err)
+		fmt.Println(string(data))
+	}
 }
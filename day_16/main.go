@@ -1,13 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+var explain = flag.Bool("explain", false, "print the valve opening schedule behind the answers")
+
 type Player struct {
 	valve    Valve
 	distance int
@@ -19,6 +24,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type Valve struct {
 	name string
 	rate int
@@ -379,12 +394,105 @@ func calculateFlowRate2(valves map[string]Valve, linkmap map[string]map[string]i
 }
 
 /*
-read a file using os.ReadFile from Args[1]. parse the file into Valve structs. for each valve,
+one step of an opening schedule. Minute is the minute during which the valve
+gets opened, it releases Rate pressure every minute after that.
+*/
+type Opening struct {
+	Valve    string `json:"valve"`
+	Minute   int    `json:"minute"`
+	Rate     int    `json:"rate"`
+	Released int    `json:"released"`
+}
+
+type Explanation struct {
+	Part1Schedule []Opening   `json:"part1_schedule"`
+	Part1Released int         `json:"part1_released"`
+	Part2Schedule [][]Opening `json:"part2_schedule"`
+	Part2Released int         `json:"part2_released"`
+}
+
+/*
+walk a route of valves starting from AA and open each valve on the way.
+moving between valves costs the distance in minutes, opening costs 1 minute.
+the route may contain AA and repeated valves, those are not opened again.
+*/
+func ScheduleRoute(route []string, valves map[string]Valve, distanceMap map[string]map[string]int, totalMinutes int) ([]Opening, int) {
+	schedule := []Opening{}
+	released := 0
+	at := "AA"
+	minute := 0
+	for _, name := range route {
+		if name == "" || name == at {
+			continue
+		}
+		minute += distanceMap[at][name] + 1
+		at = name
+		opening := Opening{
+			Valve:    name,
+			Minute:   minute,
+			Rate:     valves[name].rate,
+			Released: valves[name].rate * (totalMinutes - minute),
+		}
+		released += opening.Released
+		schedule = append(schedule, opening)
+	}
+	return schedule, released
+}
+
+/*
+the minutes of both parts, the same for the example and the real input.
+they can be changed with a json file given with -config, and flags given
+on the command line override the file.
+*/
+type Params struct {
+	Part1Minutes int `json:"part1_minutes"`
+	Part2Minutes int `json:"part2_minutes"`
+}
+
+var DefaultParams = Params{
+	Part1Minutes: 30,
+	Part2Minutes: 26,
+}
+
+var (
+	configPath       = flag.String("config", "", "json file with puzzle parameters")
+	part1MinutesFlag = flag.Int("part1-minutes", DefaultParams.Part1Minutes, "minutes until the volcano erupts in part 1")
+	part2MinutesFlag = flag.Int("part2-minutes", DefaultParams.Part2Minutes, "minutes left after teaching the elephant in part 2")
+)
+
+// the defaults with the config file and the flags given on the command line applied
+func LoadParams() Params {
+	params := DefaultParams
+	if *configPath != "" {
+		file, err := os.Open(*configPath)
+		Fatal(err)
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			Fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "part1-minutes":
+			params.Part1Minutes = *part1MinutesFlag
+		case "part2-minutes":
+			params.Part2Minutes = *part2MinutesFlag
+		}
+	})
+	return params
+}
+
+/*
+read a file using ReadInput from flag.Arg(0). parse the file into Valve structs. for each valve,
 calculate the maximum flow rate we can achieve by turning the valve. print the maximum flow rate
 */
 func main() {
+	flag.Parse()
+	params := LoadParams()
 	// Read the file
-	file, err := os.ReadFile(os.Args[1])
+	file, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 
 	// Split the file into lines
@@ -437,14 +545,28 @@ func main() {
 	// Calculate the maximum flow rate for each valve from valve 'AA'
 	opened := []string{}
 	atValve := valves["AA"]
-	bestFlowRate, bestRoute := calculateFlowRate(valves, distanceMap, opened, 31, atValve)
+	// "opening" AA costs a minute, so part 1 is searched with one extra
+	bestFlowRate, bestRoute := calculateFlowRate(valves, distanceMap, opened, params.Part1Minutes+1, atValve)
 	fmt.Println("Part1:", bestFlowRate, bestRoute)
 
 	p1 := Player{valves["AA"], -1}
 	p2 := Player{valves["AA"], -1}
 	opened = append(opened, "AA")
-	bestFlowRate, p1r, p2r := calculateFlowRate2(valves, distanceMap, opened, 26, []Player{p1, p2})
+	bestFlowRate, p1r, p2r := calculateFlowRate2(valves, distanceMap, opened, params.Part2Minutes, []Player{p1, p2})
 	fmt.Println("Part2:", bestFlowRate)
 	fmt.Println("p1:", p1r)
 	fmt.Println("p2:", p2r)
+
+	if *explain {
+		var explanation Explanation
+		explanation.Part1Schedule, explanation.Part1Released = ScheduleRoute(bestRoute, valves, distanceMap, params.Part1Minutes)
+		for _, route := range []string{p1r, p2r} {
+			schedule, released := ScheduleRoute(strings.Split(route, ","), valves, distanceMap, params.Part2Minutes)
+			explanation.Part2Schedule = append(explanation.Part2Schedule, schedule)
+			explanation.Part2Released += released
+		}
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		Fatal(err)
+		fmt.Println(string(data))
+	}
 }
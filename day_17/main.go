@@ -2,17 +2,39 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 )
 
+var (
+	checkpointEvery = flag.Int64("checkpoint-every", 0, "write the simulation state every N blocks, 0 disables checkpoints")
+	checkpointPath  = flag.String("checkpoint", "day17.checkpoint", "path of the checkpoint file")
+	resume          = flag.Bool("resume", false, "continue the simulation from the checkpoint file")
+)
+
 func Fatal(err error) {
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 func Max(a, b int) int {
 	if a > b {
 		return a
@@ -317,11 +339,145 @@ type RepeatMatcher struct {
 	gasGenIdx   int
 }
 
-func play(area Area, shapeGen Generator[Shape], gasGen Generator[rune], maxblocks int64) int64 {
+/*
+checkpoints are json files with a version number, so that a file written by
+an older build is refused instead of being misread.
+MaxBlocks tells which part the state belongs to. Params and Input, the
+InputHash of the gas pattern, tell which run it belongs to, a checkpoint of
+another run is refused. Only the rows up to the highest block are stored,
+the rest of the area is empty anyway.
+*/
+const CheckpointVersion = 2
+
+type MatchEntry struct {
+	Matcher  RepeatMatcher
+	BlockIdx int
+}
+
+type Checkpoint struct {
+	Version            int
+	Params             Params
+	Input              string
+	MaxBlocks          int64
+	BlockIdx           int64
+	SimulatedHeight    int
+	Rows               [][]byte
+	HighestBlock       int
+	ShapeGenNext       int
+	GasGenNext         int
+	TrackingGenerators *[2]int
+	Matches            []MatchEntry
+}
+
+// identifies the input a checkpoint was written for
+func InputHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// an error when the checkpoint was written with other parameters or input
+func (cp Checkpoint) Check(params Params, input string) error {
+	if cp.Params != params {
+		return fmt.Errorf("checkpoint was written with %+v, not %+v", cp.Params, params)
+	}
+	if cp.Input != input {
+		return errors.New("checkpoint was written for another gas pattern")
+	}
+	return nil
+}
+
+// RepeatMatcher is stored in checkpoints, so its fields need to be visible to encoding/json
+func (rm RepeatMatcher) MarshalJSON() ([]byte, error) {
+	return json.Marshal([4]int{rm.rowOffset, rm.patternLen, rm.shapeGenIdx, rm.gasGenIdx})
+}
+
+func (rm *RepeatMatcher) UnmarshalJSON(data []byte) error {
+	var fields [4]int
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*rm = RepeatMatcher{fields[0], fields[1], fields[2], fields[3]}
+	return nil
+}
+
+/*
+write the checkpoint to a temporary file first and rename it over the
+old one, so a crash while writing does not destroy the previous checkpoint
+*/
+func SaveCheckpoint(path string, cp Checkpoint) error {
+	cp.Version = CheckpointVersion
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("LoadCheckpoint: %s: %w", path, err)
+	}
+	if cp.Version != CheckpointVersion {
+		return cp, fmt.Errorf("LoadCheckpoint: %s: unsupported version %d", path, cp.Version)
+	}
+	return cp, nil
+}
+
+func play(area Area, shapeGen Generator[Shape], gasGen Generator[rune], maxblocks int64, params Params, input string) int64 {
 	simulatedHeight := 0
 	var trackingGenerators *Pair[int]
 	var matchCollection map[RepeatMatcher]int = make(map[RepeatMatcher]int)
-	for blockidx := int64(0); blockidx < maxblocks; blockidx++ {
+	startBlock := int64(0)
+	if *resume {
+		cp, err := LoadCheckpoint(*checkpointPath)
+		Fatal(err)
+		Fatal(cp.Check(params, input))
+		if cp.MaxBlocks == maxblocks {
+			startBlock = cp.BlockIdx
+			simulatedHeight = cp.SimulatedHeight
+			copy(area.b, cp.Rows)
+			area.highestBlock = cp.HighestBlock
+			shapeGen.next = cp.ShapeGenNext
+			gasGen.next = cp.GasGenNext
+			if cp.TrackingGenerators != nil {
+				trackingGenerators = &Pair[int]{cp.TrackingGenerators[0], cp.TrackingGenerators[1]}
+			}
+			for _, match := range cp.Matches {
+				matchCollection[match.Matcher] = match.BlockIdx
+			}
+			fmt.Println("resuming from block", startBlock)
+		}
+	}
+	for blockidx := startBlock; blockidx < maxblocks; blockidx++ {
+		if *checkpointEvery > 0 && blockidx > startBlock && blockidx%*checkpointEvery == 0 {
+			cp := Checkpoint{
+				Params:          params,
+				Input:           input,
+				MaxBlocks:       maxblocks,
+				BlockIdx:        blockidx,
+				SimulatedHeight: simulatedHeight,
+				Rows:            area.b[:area.highestBlock+1],
+				HighestBlock:    area.highestBlock,
+				ShapeGenNext:    shapeGen.next,
+				GasGenNext:      gasGen.next,
+			}
+			if trackingGenerators != nil {
+				cp.TrackingGenerators = &[2]int{trackingGenerators.a, trackingGenerators.b}
+			}
+			for rm, idx := range matchCollection {
+				cp.Matches = append(cp.Matches, MatchEntry{rm, idx})
+			}
+			Fatal(SaveCheckpoint(*checkpointPath, cp))
+		}
 		shape := shapeGen.Next()
 		area.PlaceShape(shape)
 		for {
@@ -379,25 +535,85 @@ func play(area Area, shapeGen Generator[Shape], gasGen Generator[rune], maxblock
 }
 
 /*
-load 'gasPattern' as string from file using os.ReadFile from Argv[1].
+how many rocks fall in each part, the example drops as many as the real
+input. a json file given with -config overrides the defaults, flags given
+on the command line override that again.
+*/
+type Params struct {
+	Part1Blocks int64 `json:"part1_blocks"`
+	Part2Blocks int64 `json:"part2_blocks"`
+}
+
+var DefaultParams = Params{
+	Part1Blocks: 2022,
+	Part2Blocks: 1000000000000,
+}
+
+var (
+	configPath      = flag.String("config", "", "json file with puzzle parameters")
+	part1BlocksFlag = flag.Int64("part1-blocks", DefaultParams.Part1Blocks, "rocks dropped in part 1")
+	part2BlocksFlag = flag.Int64("part2-blocks", DefaultParams.Part2Blocks, "rocks dropped in part 2")
+)
+
+// the defaults with the config file and the flags given on the command line applied
+func LoadParams() Params {
+	params := DefaultParams
+	if *configPath != "" {
+		file, err := os.Open(*configPath)
+		Fatal(err)
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			Fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "part1-blocks":
+			params.Part1Blocks = *part1BlocksFlag
+		case "part2-blocks":
+			params.Part2Blocks = *part2BlocksFlag
+		}
+	})
+	return params
+}
+
+/*
+the area needs at most 4 rows per block plus room to place the next one,
+but there is no point going past 10000000 rows because the repeating
+pattern is found long before that
+*/
+func AreaHeight(blocks int64) int {
+	if blocks > 10000000/4 {
+		return 10000000
+	}
+	return int(blocks*4) + 10
+}
+
+/*
+load 'gasPattern' as string from file using ReadInput from flag.Arg(0).
 then create a generator for the [] Shape.
 create an Area with width 7 height 8000.
 then call play() with Area and shape generator.
 */
 func main() {
-	gasPattern, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	params := LoadParams()
+	gasPattern, err := ReadInput(flag.Arg(0))
 	// trim gasPattern
 	gasPattern = bytes.Trim(gasPattern, "\n")
 	Fatal(err)
+	input := InputHash(gasPattern)
 	shapeMachine := Generator[Shape]{0, Shapes}
 	gasMachine := Generator[rune]{0, []rune(string(gasPattern))}
-	area := NewArea(7, 2022*4)
-	p1Result := play(area, shapeMachine, gasMachine, int64(2022))
+	area := NewArea(7, AreaHeight(params.Part1Blocks))
+	p1Result := play(area, shapeMachine, gasMachine, params.Part1Blocks, params, input)
 	fmt.Printf("p1: %d\n", p1Result)
 
 	shapeMachine = Generator[Shape]{0, Shapes}
 	gasMachine = Generator[rune]{0, []rune(string(gasPattern))}
-	area = NewArea(7, 10000000)
-	p2Result := play(area, shapeMachine, gasMachine, int64(1000000000000))
+	area = NewArea(7, AreaHeight(params.Part2Blocks))
+	p2Result := play(area, shapeMachine, gasMachine, params.Part2Blocks, params, input)
 	fmt.Printf("p2: %d\n", p2Result)
 }
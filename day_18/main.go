@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
 )
@@ -13,6 +15,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type BoundingBox struct {
 	x1, x2, y1, y2, z1, z2 int
 }
@@ -173,8 +185,9 @@ func propagateSteam(bb BoundingBox, cubes map[Pos]*Cube, steam *map[Pos]bool) {
 }
 
 func main() {
-	// read file Argv[1] using os.ReadFile,
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// read file flag.Arg(0) using ReadInput,
+	data, err := ReadInput(flag.Arg(0))
 	lines := bytes.Split(data, []byte{'\n'})
 	Fatal(err)
 	// remove last empty line
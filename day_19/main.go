@@ -1,11 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
+var explain = flag.Bool("explain", false, "print the robot build order behind the answers")
+
 type Pair[T any, U any] struct {
 	first  T
 	second U
@@ -24,6 +29,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type OreRobot struct {
 	oreCost int
 }
@@ -179,11 +194,35 @@ func ParseRecipes(input []string) []Recipe {
 	return recipes
 }
 
+// a robot bought at the start of Minute, it starts collecting the next minute
+type Build struct {
+	Minute int    `json:"minute"`
+	Robot  string `json:"robot"`
+}
+
+type BlueprintExplanation struct {
+	Id      int     `json:"id"`
+	Minutes int     `json:"minutes"`
+	Geodes  int     `json:"geodes"`
+	Builds  []Build `json:"builds"`
+}
+
 var highestGeodes = 0
 
-func simulate(recipe Recipe, gs GameState, minute int, maxminute int) (totalGeodes int) {
+/*
+the build in front of the builds that follow it. the list is only kept
+with -explain, copying it on every branch slows the search down.
+*/
+func withBuild(build Build, builds []Build) []Build {
+	if !*explain {
+		return nil
+	}
+	return append([]Build{build}, builds...)
+}
+
+func simulate(recipe Recipe, gs GameState, minute int, maxminute int) (totalGeodes int, builds []Build) {
 	if minute > maxminute {
-		return gs.geode
+		return gs.geode, nil
 	}
 
 	if gs.geode > highestGeodes {
@@ -198,7 +237,7 @@ func simulate(recipe Recipe, gs GameState, minute int, maxminute int) (totalGeod
 			bestCaseMiners += 1
 		}
 		if bestCaseGeodes < highestGeodes {
-			return 0
+			return 0, nil
 		}
 		// do i have obsidian to build a geode robot?
 		/*
@@ -222,25 +261,30 @@ func simulate(recipe Recipe, gs GameState, minute int, maxminute int) (totalGeod
 	notProducing := !(gs.oreRobotsInProduction > 0 || gs.clayRobotsInProduction > 0 || gs.obsidianRobotsInProduction > 0 || gs.geodeRobotsInProduction > 0)
 
 	bestBranchResult := 0
+	var bestBranchBuilds []Build
 	if notProducing && maxminute-minute >= 1 {
 		if newState := gs.BuyGeodeRobot(recipe); newState != nil {
-			if geodeCount := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
+			if geodeCount, builds := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
 				bestBranchResult = geodeCount
+				bestBranchBuilds = withBuild(Build{minute, "geode"}, builds)
 			}
 		}
 		if newState := gs.BuyObsidianRobot(recipe); newState != nil {
-			if geodeCount := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
+			if geodeCount, builds := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
 				bestBranchResult = geodeCount
+				bestBranchBuilds = withBuild(Build{minute, "obsidian"}, builds)
 			}
 		}
 		if newState := gs.BuyClayRobot(recipe); newState != nil {
-			if geodeCount := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
+			if geodeCount, builds := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
 				bestBranchResult = geodeCount
+				bestBranchBuilds = withBuild(Build{minute, "clay"}, builds)
 			}
 		}
 		if newState := gs.BuyOreRobot(recipe); newState != nil {
-			if geodeCount := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
+			if geodeCount, builds := simulate(recipe, *newState, minute, maxminute); geodeCount > bestBranchResult {
 				bestBranchResult = geodeCount
+				bestBranchBuilds = withBuild(Build{minute, "ore"}, builds)
 			}
 		}
 	}
@@ -258,23 +302,74 @@ func simulate(recipe Recipe, gs GameState, minute int, maxminute int) (totalGeod
 	gs.obsidianRobotsInProduction = 0
 	gs.geodeRobots += gs.geodeRobotsInProduction
 	gs.geodeRobotsInProduction = 0
-	simulationResult := simulate(recipe, gs, minute+1, maxminute)
+	simulationResult, simulationBuilds := simulate(recipe, gs, minute+1, maxminute)
 	//fmt.Printf("%d[%d]: %+v\n", minute, simulationResult, gs)
-	return Max(bestBranchResult, simulationResult)
+	if bestBranchResult > simulationResult {
+		return bestBranchResult, bestBranchBuilds
+	}
+	return simulationResult, simulationBuilds
 }
 
 /*
-open file Argv[1] using os.ReadFile.
+the minutes each part has to collect geodes, the same for the example
+and the real input. a json file given with -config changes them, flags
+given on the command line change them again.
+*/
+type Params struct {
+	Part1Minutes int `json:"part1_minutes"`
+	Part2Minutes int `json:"part2_minutes"`
+}
+
+var DefaultParams = Params{
+	Part1Minutes: 24,
+	Part2Minutes: 32,
+}
+
+var (
+	configPath       = flag.String("config", "", "json file with puzzle parameters")
+	part1MinutesFlag = flag.Int("part1-minutes", DefaultParams.Part1Minutes, "minutes to collect geodes in part 1")
+	part2MinutesFlag = flag.Int("part2-minutes", DefaultParams.Part2Minutes, "minutes to collect geodes in part 2")
+)
+
+// the defaults with the config file and the flags given on the command line applied
+func LoadParams() Params {
+	params := DefaultParams
+	if *configPath != "" {
+		file, err := os.Open(*configPath)
+		Fatal(err)
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			Fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "part1-minutes":
+			params.Part1Minutes = *part1MinutesFlag
+		case "part2-minutes":
+			params.Part2Minutes = *part2MinutesFlag
+		}
+	})
+	return params
+}
+
+/*
+open file flag.Arg(0) using ReadInput.
 split data into lines, remove last empty line.
 parse recipes from lines.
 */
 func main() {
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	params := LoadParams()
+	data, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	lines := strings.Split(string(data), "\n")
 	recipes := ParseRecipes(lines)
 	fmt.Println(recipes)
 
+	explanation := []BlueprintExplanation{}
 	qualityLvlSum := 0
 	for _, recipe := range recipes {
 		highestGeodes = 0
@@ -288,7 +383,8 @@ func main() {
 			obsidianRobots: 0,
 			geodeRobots:    0,
 		}
-		result := simulate(recipe, gamestate, 1, 24)
+		result, builds := simulate(recipe, gamestate, 1, params.Part1Minutes)
+		explanation = append(explanation, BlueprintExplanation{recipe.Id, params.Part1Minutes, result, builds})
 		qualityLvl := result * recipe.Id
 		qualityLvlSum += qualityLvl
 		fmt.Println("blueprint", recipe.Id, "result:", result, "new quality lvl:", qualityLvl, "total quality lvl:", qualityLvlSum)
@@ -306,8 +402,15 @@ func main() {
 			obsidianRobots: 0,
 			geodeRobots:    0,
 		}
-		result := simulate(recipe, gamestate, 1, 32)
+		result, builds := simulate(recipe, gamestate, 1, params.Part2Minutes)
+		explanation = append(explanation, BlueprintExplanation{recipe.Id, params.Part2Minutes, result, builds})
 		highestGeodes *= result
 		fmt.Println("blueprint", recipe.Id, "result:", result)
 	}
+
+	if *explain {
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		Fatal(err)
+		fmt.Println(string(data))
+	}
 }
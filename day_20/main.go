@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -22,6 +24,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 func Modulus[T Num](a, b T) T {
 	// return the modulus of a and b
 	return ((a % b) + b) % b
@@ -117,8 +129,9 @@ func run[T Num](rb RingBuffer[T], mixtimes int) T {
 }
 
 func main() {
-	// read in file using os.ReadFile from Argv[1]
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// read in file using ReadInput from flag.Arg(0)
+	data, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// split the file into lines
 	lines := strings.Split(string(data), "\n")
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"strconv"
@@ -10,6 +13,8 @@ import (
 	"github.com/JohnCGriffin/overflow"
 )
 
+var explain = flag.Bool("explain", false, "print the human value and the root equation behind the answers")
+
 /*
 Utils
 */
@@ -20,6 +25,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 /*
 Datastructures
 */
@@ -168,7 +183,7 @@ func between(a, b, arg float64) bool {
 	return (a < arg && arg < b) || (b < arg && arg < a)
 }
 
-func GradientLocalMinimaSeeker(monkeys map[string]*Monkey, results *map[string]int, result *Result) {
+func GradientLocalMinimaSeeker(monkeys map[string]*Monkey, results *map[string]int, result *Result) int {
 	min := int(math.MinInt32)
 	stepSize := int(1000000)
 	prevRatio := float64(0)
@@ -194,7 +209,7 @@ func GradientLocalMinimaSeeker(monkeys map[string]*Monkey, results *map[string]i
 
 		// check if we won
 		if left == right && result.Failure == false {
-			return
+			return value
 		}
 
 		// compute the gradient
@@ -229,9 +244,47 @@ func GradientLocalMinimaSeeker(monkeys map[string]*Monkey, results *map[string]i
 	}
 }
 
+/*
+write out the expression of a monkey with "humn" left as a variable.
+everything that does not depend on humn is folded into a single number
+using the part 1 results.
+*/
+func Equation(monkeys map[string]*Monkey, target string, results map[string]int) (string, bool) {
+	if target == "humn" {
+		return "humn", true
+	}
+	targetMonkey := monkeys[target]
+	if len(targetMonkey.Expression) == 1 {
+		return targetMonkey.Expression[0], false
+	}
+	left, leftHasHuman := Equation(monkeys, targetMonkey.Expression[0], results)
+	right, rightHasHuman := Equation(monkeys, targetMonkey.Expression[2], results)
+	if !leftHasHuman && !rightHasHuman {
+		return strconv.Itoa(results[target]), false
+	}
+	if !leftHasHuman {
+		left = strconv.Itoa(results[targetMonkey.Expression[0]])
+	}
+	if !rightHasHuman {
+		right = strconv.Itoa(results[targetMonkey.Expression[2]])
+	}
+	if targetMonkey.Expression[1] == "=" {
+		return left + " = " + right, true
+	}
+	return "(" + left + " " + targetMonkey.Expression[1] + " " + right + ")", true
+}
+
+type Explanation struct {
+	Human    int    `json:"humn"`
+	Equation string `json:"equation"`
+	Left     int    `json:"left"`
+	Right    int    `json:"right"`
+}
+
 func main() {
-	// read file from Argv[1] using os.ReadFile
-	fileData, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// read file from flag.Arg(0) using ReadInput
+	fileData, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// split it into lines
 	lines := strings.Split(string(fileData), "\n")
@@ -252,5 +305,18 @@ func main() {
 	/* Part 2 */
 	result := Result{}
 	monkeys["root"].Expression[1] = "="
-	GradientLocalMinimaSeeker(monkeys, &results, &result)
+	human := GradientLocalMinimaSeeker(monkeys, &results, &result)
+	println("Part2:", human)
+
+	if *explain {
+		// check the answer by resolving root once more with the found value
+		check := make(map[string]int)
+		check["humn"] = human
+		checkResult := Result{}
+		resolveMonkeys2(monkeys, "root", &check, &checkResult)
+		equation, _ := Equation(monkeys, "root", results)
+		data, err := json.MarshalIndent(Explanation{human, equation, checkResult.Left, checkResult.Right}, "", "  ")
+		Fatal(err)
+		fmt.Println(string(data))
+	}
 }
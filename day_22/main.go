@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -18,6 +20,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 func Abs(a int) int {
 	if a < 0 {
 		return -a
@@ -505,8 +517,9 @@ Main
 102221
 */
 func main() {
-	// parse input file from Argv[1] using os.ReadFile
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// parse input file from flag.Arg(0) using ReadInput
+	data, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// parse data into area and rules
 	area, rules := ParseData(string(data))
@@ -1,13 +1,25 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
 	"strings"
 )
 
+var (
+	checkpointEvery = flag.Int("checkpoint-every", 0, "write the simulation state every N rounds, 0 disables checkpoints")
+	checkpointPath  = flag.String("checkpoint", "day23.checkpoint", "path of the checkpoint file")
+	resume          = flag.Bool("resume", false, "continue the simulation from the checkpoint file")
+)
+
 /*
 Utils*
 */
@@ -17,6 +29,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 /*
 Structures
 */
@@ -43,6 +65,20 @@ type Point struct {
 	y int
 }
 
+// Point is stored in checkpoints, so its fields need to be visible to encoding/json
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{p.x, p.y})
+}
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var xy [2]int
+	if err := json.Unmarshal(data, &xy); err != nil {
+		return err
+	}
+	*p = Point{xy[0], xy[1]}
+	return nil
+}
+
 func (p Point) AddVector(v Vector) Point {
 	return Point{p.x + v.X, p.y + v.Y}
 }
@@ -225,9 +261,76 @@ func CalcScore(elves []Elf) int {
 	return score
 }
 
-func Task(elves []Elf) (int, int) {
+/*
+checkpoints are json files with a version number, so that a file written by
+an older build is refused instead of being misread.
+Round is the last round that was fully played. Input is the InputHash of
+the map the elves started from, a checkpoint of another map is refused.
+*/
+const CheckpointVersion = 2
+
+type Checkpoint struct {
+	Version        int
+	Input          string
+	Round          int
+	ElvesAtRound10 int
+	Elves          []Elf
+}
+
+/*
+write the checkpoint to a temporary file first and rename it over the
+old one, so a crash while writing does not destroy the previous checkpoint
+*/
+func SaveCheckpoint(path string, cp Checkpoint) error {
+	cp.Version = CheckpointVersion
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func LoadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("LoadCheckpoint: %s: %w", path, err)
+	}
+	if cp.Version != CheckpointVersion {
+		return cp, fmt.Errorf("LoadCheckpoint: %s: unsupported version %d", path, cp.Version)
+	}
+	return cp, nil
+}
+
+// identifies the input a checkpoint was written for
+func InputHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// input is the InputHash of the map
+func Task(elves []Elf, input string) (int, int) {
 	elvesAtRound10 := 0
-	for round := 1; ; round++ {
+	startRound := 1
+	if *resume {
+		cp, err := LoadCheckpoint(*checkpointPath)
+		Fatal(err)
+		if cp.Input != input {
+			Fatal(errors.New("checkpoint was written for another map"))
+		}
+		elves = cp.Elves
+		elvesAtRound10 = cp.ElvesAtRound10
+		startRound = cp.Round + 1
+		fmt.Println("resuming from round", startRound)
+	}
+	for round := startRound; ; round++ {
 		//fmt.Println("\n==== Round", round, "====\n")
 		elfAt := map[Point]*Elf{}
 		for i := range elves {
@@ -280,6 +383,9 @@ func Task(elves []Elf) (int, int) {
 		if len(proposedMoves) == 0 {
 			return elvesAtRound10, round
 		}
+		if *checkpointEvery > 0 && round%*checkpointEvery == 0 {
+			Fatal(SaveCheckpoint(*checkpointPath, Checkpoint{Input: input, Round: round, ElvesAtRound10: elvesAtRound10, Elves: elves}))
+		}
 	}
 }
 
@@ -287,10 +393,11 @@ func Task(elves []Elf) (int, int) {
 Main
 */
 func main() {
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	data, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	_, elves := ParseMap(string(data))
-	part1, part2 := Task(elves)
+	part1, part2 := Task(elves, InputHash(data))
 	fmt.Println("Part 1:", part1)
 	fmt.Println("Part 2:", part2)
 }
@@ -1,19 +1,34 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
 	"strings"
 )
 
+var explain = flag.Bool("explain", false, "print the cells of the path behind the answer")
+
 func Fatal(err error) {
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 type Point struct {
 	X, Y int
 }
@@ -23,9 +38,23 @@ type Blizzard struct {
 	Dir byte
 }
 
+// Prev is the mover one minute earlier, following it gives the walked path
 type Mover struct {
 	Pos   Point
 	Moves int
+	Prev  *Mover
+}
+
+/*
+return the positions of the mover for every minute, from the start to the
+mover itself. waiting in place shows up as the same point twice.
+*/
+func (m *Mover) Path() []Point {
+	path := make([]Point, m.Moves+1)
+	for at := m; at != nil; at = at.Prev {
+		path[at.Moves] = at.Pos
+	}
+	return path
 }
 
 type Map struct {
@@ -122,9 +151,9 @@ func moveBlizzards(bliz []Blizzard, teleport map[Point]Point) []Blizzard {
 	return bliz
 }
 
-func run2(start Point, bliz []Blizzard, mapp Map, targets []Point) int {
+func run2(start Point, bliz []Blizzard, mapp Map, targets []Point) (int, []Point) {
 	movers := make(map[Point]Mover)
-	movers[start] = Mover{start, 0}
+	movers[start] = Mover{start, 0, nil}
 
 	mapp.Finish = targets[0]
 	targets = targets[1:]
@@ -146,6 +175,7 @@ func run2(start Point, bliz []Blizzard, mapp Map, targets []Point) int {
 		newMovers := make(map[Point]Mover)
 	moversFor:
 		for _, m := range movers {
+			m := m
 			// mover is clear, propagate
 			newMoverPoints := []Point{
 				{m.Pos.X + 1, m.Pos.Y},
@@ -158,10 +188,11 @@ func run2(start Point, bliz []Blizzard, mapp Map, targets []Point) int {
 				// check if new mover is on Finish
 				if newMoverPoint == mapp.Finish {
 					if len(targets) == 0 {
-						return m.Moves + 1
+						finished := Mover{newMoverPoint, m.Moves + 1, &m}
+						return finished.Moves, finished.Path()
 					} else {
 						newMovers = make(map[Point]Mover)
-						newMovers[newMoverPoint] = Mover{newMoverPoint, m.Moves + 1}
+						newMovers[newMoverPoint] = Mover{newMoverPoint, m.Moves + 1, &m}
 						// we have a bug in the teleporter creator
 						// where target boxes are teleporters
 						delete(mapp.Teleports, newMoverPoint)
@@ -186,7 +217,7 @@ func run2(start Point, bliz []Blizzard, mapp Map, targets []Point) int {
 				if newMoverPoint.Y == -1 || newMoverPoint.Y == mapp.Lines {
 					continue
 				}
-				newMovers[newMoverPoint] = Mover{newMoverPoint, m.Moves + 1}
+				newMovers[newMoverPoint] = Mover{newMoverPoint, m.Moves + 1, &m}
 			}
 		}
 		movers = newMovers
@@ -199,8 +230,9 @@ func run2(start Point, bliz []Blizzard, mapp Map, targets []Point) int {
 442 is too high
 */
 func main() {
-	// read file from Args[1] using os.ReadFile
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// read file from flag.Arg(0) using ReadInput
+	data, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	start, blizzards, mapp := Parse(string(data))
 
@@ -212,8 +244,18 @@ func main() {
 	}
 	// for part1, remove the 2 last targets
 
-	moves := run2(start, blizzards, mapp, targets)
+	moves, path := run2(start, blizzards, mapp, targets)
 	if moves < math.MaxInt {
 		fmt.Println("Answer:", moves)
 	}
+
+	if *explain {
+		cells := [][2]int{}
+		for _, p := range path {
+			cells = append(cells, [2]int{p.X, p.Y})
+		}
+		data, err := json.Marshal(map[string][][2]int{"path": cells})
+		Fatal(err)
+		fmt.Println(string(data))
+	}
 }
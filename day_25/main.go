@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
@@ -29,6 +31,16 @@ func Fatal(err error) {
 	}
 }
 
+/*
+read the whole input. the path "-" or no path at all reads stdin.
+*/
+func ReadInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 /*
 decode format: 2=0=
 the rightmost place is the 5s place,
@@ -97,8 +109,9 @@ func Encode(inp int) (out string) {
 }
 
 func main() {
-	// read data using os.ReadFile from Argv[1]
-	data, err := os.ReadFile(os.Args[1])
+	flag.Parse()
+	// read data using ReadInput from flag.Arg(0)
+	data, err := ReadInput(flag.Arg(0))
 	Fatal(err)
 	// test decoder:
 	lines := strings.Split(string(data), "\n")